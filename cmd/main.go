@@ -2,17 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/nerfthisdev/backend-test-task/internal/auth"
 	"github.com/nerfthisdev/backend-test-task/internal/config"
 	"github.com/nerfthisdev/backend-test-task/internal/database"
+	"github.com/nerfthisdev/backend-test-task/internal/expiry"
+	"github.com/nerfthisdev/backend-test-task/internal/health"
 	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/metrics"
+	"github.com/nerfthisdev/backend-test-task/internal/ratelimit"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
 	server "github.com/nerfthisdev/backend-test-task/internal/router"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+	"github.com/nerfthisdev/backend-test-task/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
@@ -36,46 +46,137 @@ func main() {
 	cfg := config.InitConfig()
 
 	// init logger
-	logger := logging.GetLogger()
+	logger := logging.GetLogger(cfg.LogLevel)
 	logger.Info("successfully initialized logger")
+	zap.ReplaceGlobals(&logger)
 
-	// init context
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter, cfg.TraceFile)
+	if err != nil {
+		logger.Fatal("failed to init tracing", zap.Error(err))
+	}
 
+	// stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// init context
+	initCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
-	dbpool, err := database.New(ctx, cfg)
+	dbpool, err := database.New(initCtx, cfg)
 	if err != nil {
 		logger.Fatal("failed to init db", zap.Error(err))
 	}
 
 	logger.Info("successfully connected to db")
 
-	defer dbpool.Close()
-
 	// run migrations
-	err = database.RunMigrations(cfg)
+	schemaVersion, err := database.RunMigrations(cfg)
 	if err != nil {
 		logger.Fatal("failed to run migrations", zap.Error(err))
 	}
 
-	logger.Info("successfully ran migrations")
+	logger.Info("successfully ran migrations", zap.Uint("version", schemaVersion))
+
+	prometheus.MustRegister(metrics.NewPoolCollector(dbpool))
+
+	checker := health.NewChecker()
+	checker.Add("database", database.PingCheck(dbpool))
+	checker.Add("migrations", database.MigrationCheck(dbpool, schemaVersion))
+
+	store, err := storage.NewLocalStorage(cfg.StorageDir)
+	if err != nil {
+		logger.Fatal("failed to init storage", zap.Error(err))
+	}
 
 	usersRepo := repository.NewUserRepository(dbpool)
-	postsRepo := repository.NewPostRepository(dbpool)
+	postsRepo := repository.NewPostRepository(dbpool, cfg.AdLifetime)
+	categoriesRepo := repository.NewCategoryRepository(dbpool)
+	imagesRepo := repository.NewImageRepository(dbpool)
+	favoritesRepo := repository.NewFavoriteRepository(dbpool)
+	conversationsRepo := repository.NewConversationRepository(dbpool)
+	tokensRepo := repository.NewTokenRepository(dbpool)
 	tokenSvc := auth.NewJWTService(cfg)
+	sessions := auth.NewSessionManager(tokenSvc, tokensRepo, usersRepo, cfg.RefreshTTL)
 
-	router := server.NewRouter(usersRepo, postsRepo, tokenSvc, &logger)
+	lockout := repository.LockoutPolicy{
+		Threshold: cfg.LockoutThreshold,
+		Base:      cfg.LockoutBase,
+		Max:       cfg.LockoutMax,
+	}
+
+	router := server.NewRouter(server.Deps{
+		Users:         usersRepo,
+		Posts:         postsRepo,
+		Categories:    categoriesRepo,
+		Images:        imagesRepo,
+		Favorites:     favoritesRepo,
+		Conversations: conversationsRepo,
+		TokenRepo:     tokensRepo,
+		Tokens:        tokenSvc,
+		Sessions:      sessions,
+		Storage:       store,
+		Health:        checker,
+		Limiter:       ratelimit.NewMemoryLimiter(),
+		RateLimits:    cfg.RateLimits,
+		TrustProxy:    cfg.TrustProxy,
+		Lockout:       lockout,
+		MaxUploadSize: cfg.MaxUploadSize,
+		Logger:        &logger,
+	})
 
 	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: router,
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 
-	logger.Info("server starting on :" + cfg.Port)
-
-	if err := srv.ListenAndServe(); err != nil {
+	expiryDone := make(chan struct{})
+	go func() {
+		defer close(expiryDone)
+		expiry.Run(ctx, postsRepo, cfg.AdExpiryInterval)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("server starting on :" + cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		dbpool.Close()
 		logger.Fatal("server failed", zap.Error(err))
+	case <-ctx.Done():
+		// restore default handling so a second signal kills the process
+		stop()
+		logger.Info("shutting down, draining connections", zap.Duration("timeout", cfg.ShutdownTimeout))
+	}
+
+	// fail readiness first so the load balancer stops routing new requests
+	checker.Drain()
+	time.Sleep(cfg.ShutdownDelay)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown failed, closing remaining connections", zap.Error(err))
+		srv.Close()
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("server failed", zap.Error(err))
+	}
+
+	// the pool goes last so draining requests and the expiry worker can
+	// still reach the db
+	<-expiryDone
+	dbpool.Close()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", zap.Error(err))
 	}
+	logger.Info("server stopped")
 }
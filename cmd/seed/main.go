@@ -29,12 +29,13 @@ func main() {
 	}
 	defer dbpool.Close()
 
-	if err := database.RunMigrations(cfg); err != nil {
+	if _, err := database.RunMigrations(cfg); err != nil {
 		log.Fatalf("failed to run migrations: %v", err)
 	}
 
 	userRepo := repository.NewUserRepository(dbpool)
-	postRepo := repository.NewPostRepository(dbpool)
+	postRepo := repository.NewPostRepository(dbpool, cfg.AdLifetime)
+	categoryRepo := repository.NewCategoryRepository(dbpool)
 
 	gofakeit.Seed(0)
 
@@ -55,18 +56,38 @@ func main() {
 		users = append(users, u)
 	}
 
+	categories, err := categoryRepo.List(ctx)
+	if err != nil {
+		log.Fatalf("failed to list categories: %v", err)
+	}
+	var leaves []domain.Category
+	for _, c := range categories {
+		if c.ParentID != nil {
+			leaves = append(leaves, c)
+		}
+	}
+	if len(leaves) == 0 {
+		log.Fatal("no categories to seed ads into")
+	}
+
 	for _, u := range users {
 		for i := 0; i < adsPerUser; i++ {
 			post := domain.Post{
 				UserGUID:    u.GUID,
+				CategoryID:  leaves[gofakeit.Number(0, len(leaves)-1)].ID,
 				Title:       gofakeit.Sentence(3),
 				Description: gofakeit.Paragraph(1, 2, 5, " "),
 				ImageURL:    fmt.Sprintf("https://picsum.photos/seed/%d/640/480", gofakeit.Number(1, 100000)),
 				Price:       gofakeit.Price(10, 1000),
 			}
-			if _, err := postRepo.Create(ctx, post); err != nil {
+			created, err := postRepo.Create(ctx, post)
+			if err != nil {
 				log.Fatalf("failed to create post: %v", err)
 			}
+			// seeded ads skip moderation
+			if err := postRepo.Transition(ctx, created.ID, domain.AdPublished, ""); err != nil {
+				log.Fatalf("failed to publish post: %v", err)
+			}
 		}
 	}
 
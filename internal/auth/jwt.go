@@ -1,19 +1,32 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/nerfthisdev/backend-test-task/internal/config"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"golang.org/x/crypto/bcrypt"
 )
 
+const refreshTokenBytes = 32
+
 type JWTService struct {
 	secret    string
 	accessTTL time.Duration
 }
 
+type accessClaims struct {
+	SessionID string      `json:"sid"`
+	Role      domain.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
 func NewJWTService(cfg config.Config) *JWTService {
 	return &JWTService{
 		secret:    cfg.JWTSecret,
@@ -21,10 +34,14 @@ func NewJWTService(cfg config.Config) *JWTService {
 	}
 }
 
-func (s *JWTService) GenerateAccessToken(guid uuid.UUID) (string, error) {
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.RegisteredClaims{
-		Subject:   guid.String(),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTTL)),
+func (s *JWTService) GenerateAccessToken(guid uuid.UUID, sessionID string, role domain.Role) (string, error) {
+	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS512, accessClaims{
+		SessionID: sessionID,
+		Role:      role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   guid.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTTL)),
+		},
 	})
 
 	accessTokenString, err := accessToken.SignedString([]byte(s.secret))
@@ -35,6 +52,14 @@ func (s *JWTService) GenerateAccessToken(guid uuid.UUID) (string, error) {
 	return accessTokenString, nil
 }
 
+func (s *JWTService) GenerateRefreshToken() (string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func (s *JWTService) ValidateAccessToken(token string) (map[string]any, error) {
 	parsed, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -58,3 +83,27 @@ func (s *JWTService) ValidateAccessToken(token string) (map[string]any, error) {
 
 	return claims, nil
 }
+
+func (s *JWTService) HashRefreshToken(token string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (s *JWTService) CompareRefreshToken(token string, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(token)) == nil
+}
+
+func (s *JWTService) EncodeBase64(token string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(token))
+}
+
+func (s *JWTService) DecodeBase64(encoded string) (string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -2,38 +2,123 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+	roleKey      contextKey = "role"
+)
 
-func AuthMiddleware(service *JWTService) func(http.Handler) http.Handler {
+// AuthMiddleware rejects requests without a valid X-Auth-Token.
+func AuthMiddleware(service *JWTService, sessions domain.TokenRepository) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := r.Header.Get("X-Auth-Token")
-			if token == "" {
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			ctx, status := authenticate(r, service, sessions)
+			if status == http.StatusInternalServerError {
+				problem.Internal(w, "failed to check session")
+				return
+			} else if status != http.StatusOK {
+				problem.Unauthorized(w)
 				return
 			}
-			claims, err := service.ValidateAccessToken(token)
-			if err != nil {
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuthMiddleware attaches the user to the context when a valid
+// X-Auth-Token is present and lets the request through anonymously otherwise.
+func OptionalAuthMiddleware(service *JWTService, sessions domain.TokenRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ctx, status := authenticate(r, service, sessions); status == http.StatusOK {
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole lets through only users whose role includes min, it must be
+// mounted after AuthMiddleware.
+func RequireRole(min domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := RoleFromContext(r.Context())
+			if !ok {
+				problem.Unauthorized(w)
 				return
 			}
-			sub, ok := claims["sub"].(string)
-			if !ok || sub == "" {
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			if !role.AtLeast(min) {
+				problem.Forbidden(w)
 				return
 			}
-			ctx := context.WithValue(r.Context(), userIDKey, sub)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// authenticate validates the request token and its session, returning the
+// context carrying the user and session ids or the status to fail with.
+func authenticate(r *http.Request, service *JWTService, sessions domain.TokenRepository) (context.Context, int) {
+	token := r.Header.Get("X-Auth-Token")
+	if token == "" {
+		return nil, http.StatusUnauthorized
+	}
+	claims, err := service.ValidateAccessToken(token)
+	if err != nil {
+		return nil, http.StatusUnauthorized
+	}
+	sub, ok := claims["sub"].(string)
+	if !ok || sub == "" {
+		return nil, http.StatusUnauthorized
+	}
+	sid, ok := claims["sid"].(string)
+	if !ok || sid == "" {
+		return nil, http.StatusUnauthorized
+	}
+	exists, err := sessions.SessionExists(r.Context(), sid)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to check session", zap.Error(err))
+		return nil, http.StatusInternalServerError
+	}
+	if !exists {
+		return nil, http.StatusUnauthorized
+	}
+	logging.AddFields(r.Context(), zap.String("user_id", sub))
+	ctx := context.WithValue(r.Context(), userIDKey, sub)
+	ctx = context.WithValue(ctx, sessionIDKey, sid)
+	// tokens issued before roles existed carry none
+	role := domain.Role(fmt.Sprint(claims["role"]))
+	if !role.Valid() {
+		role = domain.RoleUser
+	}
+	ctx = context.WithValue(ctx, roleKey, role)
+	return ctx, http.StatusOK
+}
+
 func UserIDFromContext(ctx context.Context) (string, bool) {
 	v, ok := ctx.Value(userIDKey).(string)
 	return v, ok
 }
+
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(sessionIDKey).(string)
+	return v, ok
+}
+
+func RoleFromContext(ctx context.Context) (domain.Role, bool) {
+	v, ok := ctx.Value(roleKey).(domain.Role)
+	return v, ok
+}
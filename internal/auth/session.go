@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrUserBanned          = errors.New("user is banned")
+)
+
+// UserSource looks up the current state of a user. Sessions re-read it on
+// every refresh, so role changes and bans apply without a new login.
+type UserSource interface {
+	GetByGuid(ctx context.Context, guid uuid.UUID) (*domain.User, error)
+}
+
+// SessionManager creates and rotates refresh token sessions. The refresh
+// token handed to the client is the base64 encoded "<session id>.<secret>"
+// pair, only the hash of the secret is stored.
+type SessionManager struct {
+	tokens     domain.TokenService
+	sessions   domain.TokenRepository
+	users      UserSource
+	refreshTTL time.Duration
+}
+
+func NewSessionManager(tokens domain.TokenService, sessions domain.TokenRepository, users UserSource, refreshTTL time.Duration) *SessionManager {
+	return &SessionManager{tokens: tokens, sessions: sessions, users: users, refreshTTL: refreshTTL}
+}
+
+// Start opens a new session for the user and returns its token pair.
+func (m *SessionManager) Start(ctx context.Context, user domain.User, ip, userAgent string) (domain.TokenPair, error) {
+	return m.issue(ctx, domain.RefreshToken{
+		GUID:      user.GUID,
+		SessionID: uuid.NewString(),
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}, user.Role, "")
+}
+
+// Refresh validates the refresh token and rotates the pair of its session.
+// Presenting an outdated secret of a known session revokes that session,
+// since it means a rotated token is being replayed, and so does refreshing
+// as a banned user.
+func (m *SessionManager) Refresh(ctx context.Context, refreshToken, ip, userAgent string) (domain.TokenPair, error) {
+	sessionID, secret, err := m.parse(refreshToken)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	stored, err := m.sessions.GetRefreshToken(ctx, sessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.TokenPair{}, ErrInvalidRefreshToken
+	} else if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		if err := m.sessions.DeleteRefreshToken(ctx, sessionID); err != nil {
+			return domain.TokenPair{}, err
+		}
+		return domain.TokenPair{}, ErrRefreshTokenExpired
+	}
+
+	if !m.tokens.CompareRefreshToken(secret, stored.TokenHash) {
+		if err := m.sessions.DeleteRefreshToken(ctx, sessionID); err != nil {
+			return domain.TokenPair{}, err
+		}
+		return domain.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	user, err := m.users.GetByGuid(ctx, stored.GUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.TokenPair{}, ErrInvalidRefreshToken
+	} else if err != nil {
+		return domain.TokenPair{}, err
+	}
+	if user.BannedAt != nil {
+		if err := m.sessions.DeleteRefreshToken(ctx, sessionID); err != nil {
+			return domain.TokenPair{}, err
+		}
+		return domain.TokenPair{}, ErrUserBanned
+	}
+
+	stored.IP = ip
+	stored.UserAgent = userAgent
+
+	pair, err := m.issue(ctx, stored, user.Role, stored.TokenHash)
+	if errors.Is(err, errTokenRotated) {
+		// another refresh with the same token won the race, treat it as a
+		// replay
+		if err := m.sessions.DeleteRefreshToken(ctx, sessionID); err != nil {
+			return domain.TokenPair{}, err
+		}
+		return domain.TokenPair{}, ErrInvalidRefreshToken
+	}
+	return pair, err
+}
+
+// List returns the active sessions of the user, newest first.
+func (m *SessionManager) List(ctx context.Context, guid uuid.UUID) ([]domain.RefreshToken, error) {
+	return m.sessions.GetUserRefreshTokens(ctx, guid)
+}
+
+// RevokeOwned ends a session only if it belongs to the user, otherwise it
+// reports ErrSessionNotFound so foreign session ids are not disclosed.
+func (m *SessionManager) RevokeOwned(ctx context.Context, guid uuid.UUID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	stored, err := m.sessions.GetRefreshToken(ctx, sessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSessionNotFound
+	} else if err != nil {
+		return err
+	}
+	if stored.GUID != guid {
+		return ErrSessionNotFound
+	}
+
+	return m.sessions.DeleteRefreshToken(ctx, sessionID)
+}
+
+// Revoke ends a single session, access tokens bound to it stop being accepted.
+func (m *SessionManager) Revoke(ctx context.Context, sessionID string) error {
+	return m.sessions.DeleteRefreshToken(ctx, sessionID)
+}
+
+// RevokeAll ends every session of the user.
+func (m *SessionManager) RevokeAll(ctx context.Context, guid uuid.UUID) error {
+	return m.sessions.DeleteUserRefreshTokens(ctx, guid)
+}
+
+// errTokenRotated means the token of the session changed after it was read.
+var errTokenRotated = errors.New("refresh token already rotated")
+
+// issue creates a token pair for the session. A new session is stored as is,
+// an existing one is rotated only if its token hash is still oldHash.
+func (m *SessionManager) issue(ctx context.Context, session domain.RefreshToken, role domain.Role, oldHash string) (domain.TokenPair, error) {
+	access, err := m.tokens.GenerateAccessToken(session.GUID, session.SessionID, role)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	secret, err := m.tokens.GenerateRefreshToken()
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	session.TokenHash, err = m.tokens.HashRefreshToken(secret)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+	session.ExpiresAt = time.Now().Add(m.refreshTTL)
+
+	if oldHash == "" {
+		if err := m.sessions.StoreRefreshToken(ctx, session); err != nil {
+			return domain.TokenPair{}, err
+		}
+	} else {
+		rotated, err := m.sessions.RotateRefreshToken(ctx, session, oldHash)
+		if err != nil {
+			return domain.TokenPair{}, err
+		}
+		if !rotated {
+			return domain.TokenPair{}, errTokenRotated
+		}
+	}
+
+	return domain.TokenPair{
+		AccessToken:  access,
+		RefreshToken: m.tokens.EncodeBase64(session.SessionID + "." + secret),
+	}, nil
+}
+
+func (m *SessionManager) parse(refreshToken string) (string, string, error) {
+	decoded, err := m.tokens.DecodeBase64(refreshToken)
+	if err != nil {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	sessionID, secret, ok := strings.Cut(decoded, ".")
+	if !ok || secret == "" {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	return sessionID, secret, nil
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
 	"time"
+
+	"github.com/nerfthisdev/backend-test-task/internal/ratelimit"
 )
 
 func getEnv(key, fallback string) string {
@@ -22,19 +26,105 @@ type Config struct {
 	DBName     string
 	JWTSecret  string
 	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	StorageDir string
+	LogLevel   string
+	// TraceExporter is one of none, stdout or file, TraceFile is used by file
+	TraceExporter string
+	TraceFile     string
+	// MaxUploadSize is the largest accepted image upload in bytes
+	MaxUploadSize int64
+	// HTTP server timeouts, see net/http.Server
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownDelay keeps serving with failing readiness before draining so
+	// the load balancer has time to notice
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may drain on exit
+	ShutdownTimeout time.Duration
+	// TrustProxy takes the client address from X-Forwarded-For, enable it
+	// only behind a proxy that sets the header
+	TrustProxy bool
+	RateLimits RateLimits
+	// LockoutThreshold failed logins in a row lock an account for
+	// LockoutBase, doubling with every further lockout up to LockoutMax
+	LockoutThreshold int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+	// AdLifetime is how long an ad stays published before it is archived,
+	// expired ads are looked for every AdExpiryInterval
+	AdLifetime       time.Duration
+	AdExpiryInterval time.Duration
+}
+
+// RateLimits are the per route limits, written as "10/1m" in the env.
+type RateLimits struct {
+	LoginIP    ratelimit.Limit
+	LoginUser  ratelimit.Limit
+	RegisterIP ratelimit.Limit
+}
+
+func getRateLimit(key, fallback string) ratelimit.Limit {
+	limit, err := ratelimit.ParseLimit(getEnv(key, fallback))
+	if err != nil {
+		log.Fatalf("invalid %s: %s", key, err.Error())
+	}
+	return limit
 }
 
 func InitConfig() Config {
 	accessTTL, _ := time.ParseDuration(getEnv("ACCESS_TOKEN_TTL", "15m"))
+	refreshTTL, _ := time.ParseDuration(getEnv("REFRESH_TOKEN_TTL", "720h"))
+	maxUploadSize, _ := strconv.ParseInt(getEnv("MAX_UPLOAD_SIZE", "5242880"), 10, 64)
+	readTimeout, _ := time.ParseDuration(getEnv("HTTP_READ_TIMEOUT", "15s"))
+	readHeaderTimeout, _ := time.ParseDuration(getEnv("HTTP_READ_HEADER_TIMEOUT", "5s"))
+	writeTimeout, _ := time.ParseDuration(getEnv("HTTP_WRITE_TIMEOUT", "30s"))
+	idleTimeout, _ := time.ParseDuration(getEnv("HTTP_IDLE_TIMEOUT", "120s"))
+	shutdownDelay, _ := time.ParseDuration(getEnv("SHUTDOWN_DELAY", "0s"))
+	shutdownTimeout, _ := time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "15s"))
+	lockoutThreshold, _ := strconv.Atoi(getEnv("LOCKOUT_THRESHOLD", "5"))
+	lockoutBase, _ := time.ParseDuration(getEnv("LOCKOUT_BASE", "1m"))
+	lockoutMax, _ := time.ParseDuration(getEnv("LOCKOUT_MAX", "24h"))
+	adLifetime, _ := time.ParseDuration(getEnv("AD_LIFETIME", "720h"))
+	adExpiryInterval, _ := time.ParseDuration(getEnv("AD_EXPIRY_INTERVAL", "10m"))
 	return Config{
-		PublicHost: getEnv("PUBLIC_HOST", "http://localhost"),
-		Port:       getEnv("HTTP_PORT", "8080"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBAddress:  getEnv("DB_HOST", "db"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBName:     getEnv("DB_NAME", "taskdb"),
-		JWTSecret:  getEnv("JWT_SECRET", "abracadabra2222222000000"),
-		AccessTTL:  accessTTL,
+		PublicHost:    getEnv("PUBLIC_HOST", "http://localhost"),
+		Port:          getEnv("HTTP_PORT", "8080"),
+		DBUser:        getEnv("DB_USER", "postgres"),
+		DBPassword:    getEnv("DB_PASSWORD", "postgres"),
+		DBAddress:     getEnv("DB_HOST", "db"),
+		DBPort:        getEnv("DB_PORT", "5432"),
+		DBName:        getEnv("DB_NAME", "taskdb"),
+		JWTSecret:     getEnv("JWT_SECRET", "abracadabra2222222000000"),
+		AccessTTL:     accessTTL,
+		RefreshTTL:    refreshTTL,
+		StorageDir:    getEnv("STORAGE_DIR", "uploads"),
+		LogLevel:      getEnv("LOG_LEVEL", "info"),
+		TraceExporter: getEnv("TRACE_EXPORTER", "none"),
+		TraceFile:     getEnv("TRACE_FILE", "traces.jsonl"),
+		MaxUploadSize: maxUploadSize,
+
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		ShutdownDelay:     shutdownDelay,
+		ShutdownTimeout:   shutdownTimeout,
+
+		TrustProxy: getEnv("TRUST_PROXY", "false") == "true",
+		RateLimits: RateLimits{
+			LoginIP:    getRateLimit("RATE_LIMIT_LOGIN_IP", "20/1m"),
+			LoginUser:  getRateLimit("RATE_LIMIT_LOGIN_USER", "5/1m"),
+			RegisterIP: getRateLimit("RATE_LIMIT_REGISTER_IP", "5/1h"),
+		},
+
+		LockoutThreshold: lockoutThreshold,
+		LockoutBase:      lockoutBase,
+		LockoutMax:       lockoutMax,
+
+		AdLifetime:       adLifetime,
+		AdExpiryInterval: adExpiryInterval,
 	}
 }
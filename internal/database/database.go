@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nerfthisdev/backend-test-task/internal/config"
 )
@@ -27,6 +28,7 @@ func New(ctx context.Context, cfg config.Config) (*pgxpool.Pool, error) {
 	pgCfg.MaxConns = 25
 	pgCfg.MaxConnIdleTime = 5 * time.Minute
 	pgCfg.MaxConnLifetime = 2 * time.Hour
+	pgCfg.ConnConfig.Tracer = multitracer.New(QueryLogger{}, QueryTracer{})
 
 	pool, err := pgxpool.NewWithConfig(ctx, pgCfg)
 	if err != nil {
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PingCheck reports whether the pool can reach the database.
+func PingCheck(pool *pgxpool.Pool) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	}
+}
+
+// MigrationCheck reports whether the schema is clean and at the version the
+// service was built against.
+func MigrationCheck(pool *pgxpool.Pool, want uint) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var version int64
+		var dirty bool
+		err := pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+		if err != nil {
+			return fmt.Errorf("failed to read migration version: %w", err)
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if uint(version) != want {
+			return fmt.Errorf("schema at version %d, expected %d", version, want)
+		}
+		return nil
+	}
+}
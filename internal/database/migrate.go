@@ -11,7 +11,9 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
-func RunMigrations(cfg config.Config) error {
+// RunMigrations applies pending migrations and returns the resulting schema
+// version.
+func RunMigrations(cfg config.Config) (uint, error) {
 	dburi := fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		cfg.DBUser,
@@ -26,12 +28,18 @@ func RunMigrations(cfg config.Config) error {
 		dburi,
 	)
 	if err != nil {
-		return fmt.Errorf("migration init error: %w", err)
+		return 0, fmt.Errorf("migration init error: %w", err)
 	}
+	defer m.Close()
 
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("migration up error: %w", err)
+		return 0, fmt.Errorf("migration up error: %w", err)
 	}
 
-	return nil
+	version, _, err := m.Version()
+	if err != nil {
+		return 0, fmt.Errorf("migration version error: %w", err)
+	}
+
+	return version, nil
 }
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+// QueryLogger logs every repository query through the request logger, so
+// each statement is tied to the request that ran it. Queries are logged at
+// debug level, failures other than pgx.ErrNoRows as warnings.
+type QueryLogger struct{}
+
+func (QueryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (QueryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	logger := logging.FromContext(ctx)
+	fields := []zap.Field{
+		zap.String("sql", q.sql),
+		zap.Duration("duration", time.Since(q.start)),
+	}
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		logger.Warn("query failed", append(fields, zap.Error(data.Err))...)
+		return
+	}
+	logger.Debug("query", append(fields, zap.Int64("rows", data.CommandTag.RowsAffected()))...)
+}
+
+const instrumentation = "github.com/nerfthisdev/backend-test-task/internal/database"
+
+// QueryTracer turns every query into a child span of the request span,
+// carrying the SQL statement.
+type QueryTracer struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation, _, _ := strings.Cut(strings.TrimSpace(data.SQL), " ")
+	operation = strings.ToUpper(operation)
+	ctx, _ = otel.Tracer(instrumentation).Start(ctx, "postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
@@ -1,18 +1,81 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
 	GUID     uuid.UUID `json:"guid"`
 	Username string    `json:"username"`
 	Password string    `json:"password"`
+	Role     Role      `json:"role"`
+	// LockedUntil is set while the account is locked after failed logins
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	BannedAt    *time.Time `json:"banned_at,omitempty"`
+	BanReason   string     `json:"ban_reason,omitempty"`
+}
+
+// LoginAttempt is a failed login recorded against an account.
+type LoginAttempt struct {
+	ID        int64     `json:"id"`
+	UserGUID  uuid.UUID `json:"user_guid"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Conversation is the message thread between a buyer and the author of an ad.
+type Conversation struct {
+	ID            int64     `json:"id"`
+	PostID        int64     `json:"post_id"`
+	BuyerGUID     uuid.UUID `json:"buyer_guid"`
+	SellerGUID    uuid.UUID `json:"seller_guid"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+}
+
+// HasParticipant reports whether the user is the buyer or the seller.
+func (c Conversation) HasParticipant(guid uuid.UUID) bool {
+	return c.BuyerGUID == guid || c.SellerGUID == guid
+}
+
+type Message struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversation_id"`
+	SenderGUID     uuid.UUID `json:"sender_guid"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type Post struct {
-	ID          int64     `json:"id"`
+	ID          int64       `json:"id"`
+	UserGUID    uuid.UUID   `json:"user_guid"`
+	CategoryID  int64       `json:"category_id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Images      []uuid.UUID `json:"images"`
+	ImageURL    string      `json:"image_url"`
+	Price       float64     `json:"price"`
+	Status      AdStatus    `json:"status"`
+	// RejectionReason is set by a moderator when rejecting the ad
+	RejectionReason string `json:"rejection_reason,omitempty"`
+	// ExpiresAt is when a published ad gets archived unless renewed
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Category struct {
+	ID       int64  `json:"id"`
+	ParentID *int64 `json:"parent_id"`
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+}
+
+type Image struct {
+	ID          uuid.UUID `json:"id"`
 	UserGUID    uuid.UUID `json:"user_guid"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	ImageURL    string    `json:"image_url"`
-	Price       float64   `json:"price"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -8,8 +8,11 @@ import (
 
 type TokenRepository interface {
 	StoreRefreshToken(ctx context.Context, token RefreshToken) error
-	GetRefreshToken(ctx context.Context, guid uuid.UUID) (RefreshToken, error)
-	DeleteRefreshToken(ctx context.Context, guid uuid.UUID) error
+	RotateRefreshToken(ctx context.Context, token RefreshToken, oldHash string) (bool, error)
+	GetRefreshToken(ctx context.Context, sessionID string) (RefreshToken, error)
+	GetUserRefreshTokens(ctx context.Context, guid uuid.UUID) ([]RefreshToken, error)
+	DeleteRefreshToken(ctx context.Context, sessionID string) error
+	DeleteUserRefreshTokens(ctx context.Context, guid uuid.UUID) error
 	SessionExists(ctx context.Context, sessionID string) (bool, error)
 }
 
@@ -0,0 +1,26 @@
+package domain
+
+// Role grants permissions, every role includes those of the roles below it.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// AtLeast reports whether r has all permissions of min.
+func (r Role) AtLeast(min Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[min]
+}
@@ -0,0 +1,50 @@
+package domain
+
+// AdStatus is the lifecycle state of an ad, only published ads are public.
+type AdStatus string
+
+const (
+	AdDraft         AdStatus = "draft"
+	AdPendingReview AdStatus = "pending_review"
+	AdPublished     AdStatus = "published"
+	AdRejected      AdStatus = "rejected"
+	AdArchived      AdStatus = "archived"
+	AdSold          AdStatus = "sold"
+)
+
+// adTransitions lists the statuses each status may move to. Publishing is
+// only possible from review, so every public ad has passed moderation.
+var adTransitions = map[AdStatus][]AdStatus{
+	AdDraft:         {AdPendingReview, AdArchived},
+	AdPendingReview: {AdPublished, AdRejected, AdDraft},
+	AdPublished:     {AdPendingReview, AdArchived, AdSold},
+	AdRejected:      {AdPendingReview, AdDraft, AdArchived},
+	AdArchived:      {AdPendingReview, AdDraft},
+	AdSold:          {AdArchived},
+}
+
+func (s AdStatus) Valid() bool {
+	_, ok := adTransitions[s]
+	return ok
+}
+
+// CanTransition reports whether an ad in status s may move to status to.
+func (s AdStatus) CanTransition(to AdStatus) bool {
+	for _, next := range adTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionSources returns every status that may move to status to.
+func TransitionSources(to AdStatus) []AdStatus {
+	var sources []AdStatus
+	for from := range adTransitions {
+		if from.CanTransition(to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
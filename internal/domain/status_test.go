@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+var allStatuses = []AdStatus{AdDraft, AdPendingReview, AdPublished, AdRejected, AdArchived, AdSold}
+
+func TestAdStatusValid(t *testing.T) {
+	for _, s := range allStatuses {
+		if !s.Valid() {
+			t.Errorf("%s.Valid() = false", s)
+		}
+	}
+	for _, s := range []AdStatus{"", "deleted", "PUBLISHED"} {
+		if s.Valid() {
+			t.Errorf("%q.Valid() = true", s)
+		}
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	// every allowed move, anything not listed must be rejected
+	allowed := map[[2]AdStatus]bool{
+		{AdDraft, AdPendingReview}: true,
+		{AdDraft, AdArchived}:      true,
+
+		{AdPendingReview, AdPublished}: true,
+		{AdPendingReview, AdRejected}:  true,
+		{AdPendingReview, AdDraft}:     true,
+
+		{AdPublished, AdPendingReview}: true,
+		{AdPublished, AdArchived}:      true,
+		{AdPublished, AdSold}:          true,
+
+		{AdRejected, AdPendingReview}: true,
+		{AdRejected, AdDraft}:         true,
+		{AdRejected, AdArchived}:      true,
+
+		{AdArchived, AdPendingReview}: true,
+		{AdArchived, AdDraft}:         true,
+
+		{AdSold, AdArchived}: true,
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			want := allowed[[2]AdStatus{from, to}]
+			if got := from.CanTransition(to); got != want {
+				t.Errorf("%s.CanTransition(%s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	for _, s := range allStatuses {
+		if AdStatus("unknown").CanTransition(s) {
+			t.Errorf("unknown status may move to %s", s)
+		}
+		if s.CanTransition("unknown") {
+			t.Errorf("%s may move to an unknown status", s)
+		}
+	}
+}
+
+func TestTransitionSources(t *testing.T) {
+	tests := []struct {
+		to   AdStatus
+		want []AdStatus
+	}{
+		// publishing only ever happens through review
+		{AdPublished, []AdStatus{AdPendingReview}},
+		{AdRejected, []AdStatus{AdPendingReview}},
+		{AdPendingReview, []AdStatus{AdDraft, AdPublished, AdRejected, AdArchived}},
+		{AdDraft, []AdStatus{AdPendingReview, AdRejected, AdArchived}},
+		{AdArchived, []AdStatus{AdDraft, AdPublished, AdRejected, AdSold}},
+		{AdSold, []AdStatus{AdPublished}},
+		{"unknown", nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.to), func(t *testing.T) {
+			got := TransitionSources(tt.to)
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("TransitionSources(%s) = %v, want %v", tt.to, got, want)
+			}
+		})
+	}
+}
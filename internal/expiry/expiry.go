@@ -0,0 +1,49 @@
+// Package expiry archives published ads once their lifetime is over.
+package expiry
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/metrics"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+// Run archives expired ads every interval until ctx is done. The first sweep
+// happens right away so ads that expired while the service was down don't
+// wait a full interval.
+func Run(ctx context.Context, posts *repository.PostRepository, interval time.Duration) {
+	if interval <= 0 {
+		zap.L().Warn("ad expiry disabled", zap.Duration("interval", interval))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sweep(ctx, posts)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sweep(ctx context.Context, posts *repository.PostRepository) {
+	archived, err := posts.ArchiveExpired(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			zap.L().Error("failed to archive expired ads", zap.Error(err))
+		}
+		return
+	}
+	if archived > 0 {
+		metrics.AdsExpired.Add(float64(archived))
+		zap.L().Info("archived expired ads", zap.Int64("count", archived))
+	}
+}
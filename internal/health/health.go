@@ -0,0 +1,86 @@
+// Package health tracks the dependencies the service needs to accept
+// traffic and whether it is shutting down.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports whether a single dependency is usable.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one named check.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the registered readiness checks. It is safe for concurrent use.
+type Checker struct {
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Add registers a check, it must be called before the server starts.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain marks the service as shutting down, readiness fails from now on.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+var errDraining = errors.New("server is shutting down")
+
+// Ready runs every check concurrently and reports each result by name. The
+// service is ready only if all of them pass and it is not draining.
+func (c *Checker) Ready(ctx context.Context) (map[string]Result, bool) {
+	results := make(map[string]Result, len(c.checks)+1)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := Result{Status: StatusOK}
+			if err := nc.check(ctx); err != nil {
+				res = Result{Status: StatusFail, Error: err.Error()}
+			}
+			mu.Lock()
+			results[nc.name] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	results["shutdown"] = Result{Status: StatusOK}
+	if c.draining.Load() {
+		results["shutdown"] = Result{Status: StatusFail, Error: errDraining.Error()}
+	}
+
+	for _, res := range results {
+		if res.Status != StatusOK {
+			return results, false
+		}
+	}
+	return results, true
+}
@@ -0,0 +1,61 @@
+// Package httpx holds small net/http helpers shared by the middlewares.
+package httpx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// StatusRecorder remembers the status code written through it.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// Record wraps w in a StatusRecorder. If w already is one it is returned as
+// is, so stacked middlewares share a single wrapper per request.
+func Record(w http.ResponseWriter) *StatusRecorder {
+	if rec, ok := w.(*StatusRecorder); ok {
+		return rec
+	}
+	return &StatusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *StatusRecorder) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *StatusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Status is the code sent to the client, 200 if the handler never set one.
+func (w *StatusRecorder) Status() int {
+	return w.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *StatusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Route returns the path of the pattern the ServeMux matched for r, without
+// the method, or "" if no pattern matched. The mux sets the pattern on the
+// request it was given, so call it after the mux has served r.
+func Route(r *http.Request) string {
+	if r.Pattern == "" {
+		return ""
+	}
+	// patterns look like "GET /api/v1/ads/{id}"
+	_, path, found := strings.Cut(r.Pattern, " ")
+	if !found {
+		return r.Pattern
+	}
+	return path
+}
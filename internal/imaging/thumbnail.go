@@ -0,0 +1,110 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+
+	// decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Thumbnail bounding boxes in pixels, every uploaded image gets both.
+const (
+	ThumbnailSmall  = 200
+	ThumbnailMedium = 640
+)
+
+var ThumbnailSizes = []int{ThumbnailSmall, ThumbnailMedium}
+
+// maxPixels guards against decompression bombs, images are rejected before
+// being decoded if they are larger than this. 16 MP still fits photos from
+// most phone cameras.
+const maxPixels = 16_000_000
+
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// Decode reads a jpeg, png or gif image after checking its dimensions.
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Thumbnail scales img down to fit into a size x size box keeping its aspect
+// ratio and encodes it as jpeg. Smaller images are not enlarged.
+func Thumbnail(img image.Image, size int) ([]byte, error) {
+	scaled := scale(img, size)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scale resizes by averaging the source pixels covered by each destination
+// pixel, which is enough for downscaling photos. Transparent areas are
+// flattened onto white since jpeg has no alpha channel. The source is
+// converted one band of rows at a time, so memory stays proportional to the
+// width of the image rather than its area.
+func scale(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+
+	// a destination row covers at most this many source rows
+	band := image.NewRGBA(image.Rect(0, 0, srcW, (srcH+dstH-1)/dstH+1))
+	white := image.NewUniform(color.White)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		rows := image.Rect(0, 0, srcW, y1-y0)
+		draw.Draw(band, rows, white, image.Point{}, draw.Src)
+		draw.Draw(band, rows, img, image.Pt(b.Min.X, b.Min.Y+y0), draw.Over)
+
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, bl, n uint64
+			for sy := 0; sy < y1-y0; sy++ {
+				row := band.Pix[sy*band.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+3]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					bl += uint64(p[2])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey string
+
+const scopeKey contextKey = "logScope"
+
+// scope is shared by everything handling one request, so fields added deep
+// in the chain, like the user id, also show up in the access log line.
+type scope struct {
+	logger    *zap.Logger
+	requestID string
+}
+
+func withScope(ctx context.Context, s *scope) context.Context {
+	return context.WithValue(ctx, scopeKey, s)
+}
+
+// FromContext returns the request scoped logger, or the global one outside
+// of a request.
+func FromContext(ctx context.Context) *zap.Logger {
+	if s, ok := ctx.Value(scopeKey).(*scope); ok {
+		return s.logger
+	}
+	return zap.L()
+}
+
+// AddFields attaches fields to every later log line of the request.
+func AddFields(ctx context.Context, fields ...zap.Field) {
+	if s, ok := ctx.Value(scopeKey).(*scope); ok {
+		s.logger = s.logger.With(fields...)
+	}
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(scopeKey).(*scope)
+	if !ok {
+		return "", false
+	}
+	return s.requestID, true
+}
@@ -7,8 +7,13 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func GetLogger() zap.Logger {
+// GetLogger builds the JSON production logger, level is a zap level name
+// such as "debug" or "info".
+func GetLogger(level string) zap.Logger {
 	zapConfig := zap.NewProductionConfig()
+	if lvl, err := zap.ParseAtomicLevel(level); err == nil {
+		zapConfig.Level = lvl
+	}
 	zapConfig.EncoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
 	zapConfig.DisableStacktrace = true
 	zapConfig.DisableCaller = true
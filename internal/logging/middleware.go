@@ -4,32 +4,54 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/httpx"
 )
 
-type loggingResponseWriter struct {
-	http.ResponseWriter
-	status int
-}
+const RequestIDHeader = "X-Request-ID"
 
-func (w *loggingResponseWriter) WriteHeader(code int) {
-	w.status = code
-	w.ResponseWriter.WriteHeader(code)
-}
+// maxRequestIDLength bounds ids accepted from clients and proxies.
+const maxRequestIDLength = 128
 
+// LoggingMiddleware tags each request with an X-Request-ID, taken from the
+// request when it looks sane or generated otherwise, echoes it back, stores a
+// logger carrying it in the context and logs the request once it completes.
 func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			lrw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			s := &scope{logger: logger.With(zap.String("request_id", requestID)), requestID: requestID}
+			rec := httpx.Record(w)
 			start := time.Now()
-			next.ServeHTTP(lrw, r)
-			logger.Info("request completed",
+			next.ServeHTTP(rec, r.WithContext(withScope(r.Context(), s)))
+			s.logger.Info("request completed",
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
-				zap.Int("status", lrw.status),
+				zap.Int("status", rec.Status()),
 				zap.Duration("duration", time.Since(start)),
 				zap.String("remote_addr", r.RemoteAddr),
 			)
 		})
 	}
 }
+
+// validRequestID accepts printable ASCII without spaces so ids can't inject
+// anything into headers or logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "marketplace"
+
+// Login outcomes used as the result label of LoginAttempts.
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	LoginLocked  = "locked"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+
+	AdsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ads_created_total",
+		Help:      "Ads successfully created.",
+	})
+
+	AdsExpired = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ads_expired_total",
+		Help:      "Published ads archived after expiry.",
+	})
+)
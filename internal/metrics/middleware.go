@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nerfthisdev/backend-test-task/internal/httpx"
+)
+
+// unmatchedRoute labels requests no route pattern matched, so scanners
+// hitting random paths cannot blow up label cardinality.
+const unmatchedRoute = "unmatched"
+
+// Middleware records request count and latency labelled by the route
+// pattern the ServeMux matched, it must wrap the mux itself so the pattern
+// is set once next returns.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httpx.Record(w)
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		// the method is a label of its own
+		route := httpx.Route(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(rec.Status())).Inc()
+		requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports pgxpool statistics, read on every scrape.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns *prometheus.Desc
+	idleConns     *prometheus.Desc
+	totalConns    *prometheus.Desc
+	maxConns      *prometheus.Desc
+	acquireCount  *prometheus.Desc
+	acquireWait   *prometheus.Desc
+	emptyAcquire  *prometheus.Desc
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:          pool,
+		acquiredConns: desc("acquired_conns", "Connections currently in use."),
+		idleConns:     desc("idle_conns", "Idle connections in the pool."),
+		totalConns:    desc("total_conns", "Open connections in the pool."),
+		maxConns:      desc("max_conns", "Maximum size of the pool."),
+		acquireCount:  desc("acquires_total", "Successful connection acquires."),
+		acquireWait:   desc("acquire_wait_seconds_total", "Time spent waiting for a connection when none was idle."),
+		emptyAcquire:  desc("empty_acquires_total", "Acquires that had to wait because no connection was idle."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireWait
+	ch <- c.emptyAcquire
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, s.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+}
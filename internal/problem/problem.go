@@ -0,0 +1,110 @@
+// Package problem writes RFC 7807 application/problem+json error responses.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// Machine readable error codes, clients should switch on these rather than
+// on the human readable detail.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeAccountLocked        = "account_locked"
+	CodeUserBanned           = "user_banned"
+	CodeInvalidRefreshToken  = "invalid_refresh_token"
+	CodeRefreshTokenExpired  = "refresh_token_expired"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeUserExists           = "user_exists"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInvalidTransition    = "invalid_status_transition"
+	CodeInternal             = "internal_error"
+)
+
+// Field validation codes used in FieldError.
+const (
+	FieldRequired  = "required"
+	FieldTooShort  = "too_short"
+	FieldTooLong   = "too_long"
+	FieldTooSmall  = "too_small"
+	FieldTooLarge  = "too_large"
+	FieldInvalid   = "invalid"
+	FieldNotFound  = "not_found"
+	FieldDuplicate = "duplicate"
+	FieldUnknown   = "unknown"
+)
+
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Code   string       `json:"code"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Write sends a problem with the given status, code and detail.
+func Write(w http.ResponseWriter, status int, code, detail string) {
+	Send(w, Problem{Status: status, Code: code, Detail: detail})
+}
+
+// Send writes p, filling in the type and title when they are empty.
+func Send(w http.ResponseWriter, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Validation reports every rejected field at once.
+func Validation(w http.ResponseWriter, errs []FieldError) {
+	Send(w, Problem{
+		Status: http.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: "request validation failed",
+		Errors: errs,
+	})
+}
+
+func BadRequest(w http.ResponseWriter, detail string) {
+	Write(w, http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+func Unauthorized(w http.ResponseWriter) {
+	Write(w, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid access token")
+}
+
+func Forbidden(w http.ResponseWriter) {
+	Write(w, http.StatusForbidden, CodeForbidden, "not allowed to access this resource")
+}
+
+func NotFound(w http.ResponseWriter) {
+	Write(w, http.StatusNotFound, CodeNotFound, "resource not found")
+}
+
+// Internal reports a server side failure, detail must not leak internals.
+func Internal(w http.ResponseWriter, detail string) {
+	Write(w, http.StatusInternalServerError, CodeInternal, detail)
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped to bound memory.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled completely
+	full time.Time
+}
+
+// MemoryLimiter keeps buckets in process memory.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (m *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	if !limit.Enabled() {
+		return true, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	capacity := float64(limit.Requests)
+	perToken := limit.Per / time.Duration(limit.Requests)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(perToken)
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		retryAfter := time.Duration((1 - b.tokens) * float64(perToken))
+		return false, retryAfter, nil
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((capacity - b.tokens) * float64(perToken)))
+	return true, 0, nil
+}
+
+// sweep drops buckets that have refilled, they behave exactly like missing
+// ones. It runs at most once per sweepInterval and must hold m.mu.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
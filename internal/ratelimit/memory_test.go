@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for MemoryLimiter.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter() (*MemoryLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemoryLimiter()
+	m.now = clock.now
+	return m, clock
+}
+
+func TestMemoryLimiterAllow(t *testing.T) {
+	limit := Limit{Requests: 3, Per: 3 * time.Second} // one token per second
+
+	type step struct {
+		advance    time.Duration
+		allowed    bool
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"burst up to capacity then reject", []step{
+			{0, true, 0},
+			{0, true, 0},
+			{0, true, 0},
+			{0, false, time.Second},
+		}},
+		{"retry after shrinks as tokens refill", []step{
+			{0, true, 0},
+			{0, true, 0},
+			{0, true, 0},
+			{250 * time.Millisecond, false, 750 * time.Millisecond},
+			{500 * time.Millisecond, false, 250 * time.Millisecond},
+			{250 * time.Millisecond, true, 0},
+			{0, false, time.Second},
+		}},
+		{"refill is capped at capacity", []step{
+			{0, true, 0},
+			{time.Hour, true, 0},
+			{0, true, 0},
+			{0, true, 0},
+			{0, false, time.Second},
+		}},
+		{"rejections don't consume tokens", []step{
+			{0, true, 0},
+			{0, true, 0},
+			{0, true, 0},
+			{0, false, time.Second},
+			{0, false, time.Second},
+			{time.Second, true, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, clock := newTestLimiter()
+			for i, s := range tt.steps {
+				clock.advance(s.advance)
+				allowed, retryAfter, err := m.Allow(context.Background(), "k", limit)
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if allowed != s.allowed || retryAfter != s.retryAfter {
+					t.Fatalf("step %d: Allow() = %v, %v, want %v, %v", i, allowed, retryAfter, s.allowed, s.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterKeysAreIndependent(t *testing.T) {
+	m, _ := newTestLimiter()
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	if ok, _, _ := m.Allow(context.Background(), "a", limit); !ok {
+		t.Fatal("first request for a rejected")
+	}
+	if ok, _, _ := m.Allow(context.Background(), "a", limit); ok {
+		t.Fatal("second request for a allowed")
+	}
+	if ok, _, _ := m.Allow(context.Background(), "b", limit); !ok {
+		t.Fatal("first request for b rejected")
+	}
+}
+
+func TestMemoryLimiterDisabledLimit(t *testing.T) {
+	m, _ := newTestLimiter()
+	for i := 0; i < 100; i++ {
+		if ok, _, _ := m.Allow(context.Background(), "k", Limit{}); !ok {
+			t.Fatal("zero limit rejected a request")
+		}
+	}
+	if len(m.buckets) != 0 {
+		t.Errorf("zero limit created %d buckets", len(m.buckets))
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	m, clock := newTestLimiter()
+	ctx := context.Background()
+	fast := Limit{Requests: 1, Per: time.Second}
+	slow := Limit{Requests: 1, Per: time.Hour}
+
+	m.Allow(ctx, "fast", fast)
+	m.Allow(ctx, "slow", slow)
+
+	// sweeps run at most once per interval
+	clock.advance(sweepInterval / 2)
+	m.Allow(ctx, "other", fast)
+	if _, ok := m.buckets["fast"]; !ok {
+		t.Fatal("bucket swept before the sweep interval passed")
+	}
+
+	clock.advance(sweepInterval)
+	m.Allow(ctx, "other", fast)
+	if _, ok := m.buckets["fast"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	if _, ok := m.buckets["slow"]; !ok {
+		t.Error("bucket still refilling was swept")
+	}
+
+	// a swept bucket behaves like a full one
+	if ok, _, _ := m.Allow(ctx, "fast", fast); !ok {
+		t.Error("request after sweep rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+// Middleware applies limit to the bucket key returns for each request,
+// prefixed by name so routes don't share buckets. Requests key maps to ""
+// are not limited. If the limiter fails the request is let through, a
+// broken store must not lock everyone out.
+func Middleware(l Limiter, name string, limit Limit, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, retryAfter, err := l.Allow(r.Context(), name+":"+k, limit)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limiter failed", zap.String("limit", name), zap.Error(err))
+			} else if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				problem.Write(w, http.StatusTooManyRequests, problem.CodeRateLimited,
+					"too many requests, retry in "+(time.Duration(max(seconds, 1))*time.Second).String())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
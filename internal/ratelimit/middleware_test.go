@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stubLimiter answers every Allow with the same result and records the key.
+type stubLimiter struct {
+	allowed    bool
+	retryAfter time.Duration
+	err        error
+	key        string
+}
+
+func (s *stubLimiter) Allow(_ context.Context, key string, _ Limit) (bool, time.Duration, error) {
+	s.key = key
+	return s.allowed, s.retryAfter, s.err
+}
+
+func TestMiddleware(t *testing.T) {
+	limit := Limit{Requests: 1, Per: time.Minute}
+
+	tests := []struct {
+		name       string
+		limiter    *stubLimiter
+		limit      Limit
+		key        string
+		wantStatus int
+		wantRetry  string
+		wantKey    string
+	}{
+		{"allowed", &stubLimiter{allowed: true}, limit, "1.2.3.4", http.StatusOK, "", "login:1.2.3.4"},
+		{"rejected rounds retry up", &stubLimiter{retryAfter: 1500 * time.Millisecond}, limit, "k", http.StatusTooManyRequests, "2", "login:k"},
+		{"rejected whole seconds", &stubLimiter{retryAfter: 3 * time.Second}, limit, "k", http.StatusTooManyRequests, "3", "login:k"},
+		{"retry after is at least a second", &stubLimiter{retryAfter: time.Millisecond}, limit, "k", http.StatusTooManyRequests, "1", "login:k"},
+		{"empty key is not limited", &stubLimiter{}, limit, "", http.StatusOK, "", ""},
+		{"limiter errors let requests through", &stubLimiter{err: errors.New("store down")}, limit, "k", http.StatusOK, "", "login:k"},
+		{"disabled limit skips the limiter", &stubLimiter{}, Limit{}, "k", http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			key := func(*http.Request) string { return tt.key }
+			h := Middleware(tt.limiter, "login", tt.limit, key)(next)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/login", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetry {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetry)
+			}
+			if tt.limiter.key != tt.wantKey {
+				t.Errorf("bucket key = %q, want %q", tt.limiter.key, tt.wantKey)
+			}
+		})
+	}
+}
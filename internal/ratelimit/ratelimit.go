@@ -0,0 +1,50 @@
+// Package ratelimit throttles requests with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows bursts of Requests that refill evenly over Per. The zero
+// Limit is unlimited.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// ParseLimit reads limits written as "<requests>/<duration>", e.g. "10/1m".
+// An empty string or "0" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+	n, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 10/1m", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has a bad request count", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has a bad duration", s)
+	}
+	return Limit{Requests: requests, Per: d}, nil
+}
+
+// Limiter keeps one bucket per key. MemoryLimiter serves a single instance,
+// a shared store such as Redis can implement it for several replicas.
+type Limiter interface {
+	// Allow takes a token from the bucket of key. When the bucket is empty it
+	// reports how long until the next token is available.
+	Allow(ctx context.Context, key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"", Limit{}, false},
+		{"0", Limit{}, false},
+		{"10/1m", Limit{Requests: 10, Per: time.Minute}, false},
+		{"5/1h", Limit{Requests: 5, Per: time.Hour}, false},
+		{"0/1m", Limit{Requests: 0, Per: time.Minute}, false},
+		{"10", Limit{}, true},
+		{"x/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"10/soon", Limit{}, true},
+		{"10/0s", Limit{}, true},
+		{"10/-1m", Limit{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitEnabled(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  bool
+	}{
+		{Limit{}, false},
+		{Limit{Requests: 1}, false},
+		{Limit{Per: time.Second}, false},
+		{Limit{Requests: 1, Per: time.Second}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Enabled(); got != tt.want {
+			t.Errorf("%+v.Enabled() = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
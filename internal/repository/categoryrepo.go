@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+)
+
+type CategoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCategoryRepository(db *pgxpool.Pool) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+func (r *CategoryRepository) List(ctx context.Context) ([]domain.Category, error) {
+	query := `SELECT id, parent_id, slug, name FROM categories ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []domain.Category
+	for rows.Next() {
+		var c domain.Category
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Slug, &c.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1)`
+
+	var exists bool
+	err := r.db.QueryRow(ctx, query, id).Scan(&exists)
+
+	return exists, err
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+)
+
+type ConversationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewConversationRepository(db *pgxpool.Pool) *ConversationRepository {
+	return &ConversationRepository{db: db}
+}
+
+// ConversationSummary is a conversation as seen by one of its participants.
+type ConversationSummary struct {
+	domain.Conversation
+	AdTitle string
+	// OtherUsername is the login of the other participant
+	OtherUsername string
+	LastMessage   string
+	Unread        int
+}
+
+const conversationColumns = `id, post_id, buyer_guid, seller_guid, created_at, last_message_at`
+
+func scanConversation(row pgx.Row) (*domain.Conversation, error) {
+	var c domain.Conversation
+	if err := row.Scan(&c.ID, &c.PostID, &c.BuyerGUID, &c.SellerGUID, &c.CreatedAt, &c.LastMessageAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *ConversationRepository) Get(ctx context.Context, id int64) (*domain.Conversation, error) {
+	query := `SELECT ` + conversationColumns + ` FROM conversations WHERE id = $1`
+
+	return scanConversation(r.db.QueryRow(ctx, query, id))
+}
+
+// Send adds a message from the buyer to the seller of the post, the
+// conversation is opened on the first message.
+func (r *ConversationRepository) Send(ctx context.Context, postID int64, buyer, seller uuid.UUID, body string) (*domain.Message, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	// the no-op update makes RETURNING yield the existing row on conflict
+	query := `INSERT INTO conversations (post_id, buyer_guid, seller_guid) VALUES ($1, $2, $3)
+              ON CONFLICT (post_id, buyer_guid) DO UPDATE SET post_id = EXCLUDED.post_id
+              RETURNING ` + conversationColumns
+	conv, err := scanConversation(tx.QueryRow(ctx, query, postID, buyer, seller))
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := addMessage(ctx, tx, conv.ID, buyer, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, tx.Commit(ctx)
+}
+
+// Reply adds a message from sender to an existing conversation.
+func (r *ConversationRepository) Reply(ctx context.Context, conversationID int64, sender uuid.UUID, body string) (*domain.Message, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	msg, err := addMessage(ctx, tx, conversationID, sender, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, tx.Commit(ctx)
+}
+
+// addMessage stores the message and bumps the conversation, the sender has
+// read everything up to their own message.
+func addMessage(ctx context.Context, tx pgx.Tx, conversationID int64, sender uuid.UUID, body string) (*domain.Message, error) {
+	msg := domain.Message{ConversationID: conversationID, SenderGUID: sender, Body: body}
+
+	query := `INSERT INTO messages (conversation_id, sender_guid, body) VALUES ($1, $2, $3) RETURNING id, created_at`
+	if err := tx.QueryRow(ctx, query, conversationID, sender, body).Scan(&msg.ID, &msg.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE conversations SET last_message_at = $2,
+                buyer_last_read = CASE WHEN buyer_guid = $3 THEN $4 ELSE buyer_last_read END,
+                seller_last_read = CASE WHEN seller_guid = $3 THEN $4 ELSE seller_last_read END
+              WHERE id = $1`
+	if _, err := tx.Exec(ctx, query, conversationID, msg.CreatedAt, sender, msg.ID); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// ListForUser returns the conversations the user takes part in, most
+// recently active first, with the number of messages they haven't read.
+func (r *ConversationRepository) ListForUser(ctx context.Context, user uuid.UUID, limit, offset int) ([]ConversationSummary, error) {
+	query := `SELECT c.id, c.post_id, c.buyer_guid, c.seller_guid, c.created_at, c.last_message_at,
+                     p.title, other.username, COALESCE(last.body, ''),
+                     (SELECT COUNT(*) FROM messages m
+                       WHERE m.conversation_id = c.id AND m.sender_guid <> $1
+                         AND m.id > CASE WHEN c.buyer_guid = $1 THEN c.buyer_last_read ELSE c.seller_last_read END)
+                FROM conversations c
+                JOIN posts p ON p.id = c.post_id
+                JOIN users other ON other.guid = CASE WHEN c.buyer_guid = $1 THEN c.seller_guid ELSE c.buyer_guid END
+                LEFT JOIN LATERAL (
+                    SELECT body FROM messages WHERE conversation_id = c.id ORDER BY id DESC LIMIT 1
+                ) last ON TRUE
+               WHERE c.buyer_guid = $1 OR c.seller_guid = $1
+               ORDER BY c.last_message_at DESC, c.id DESC
+               LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, user, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	convs := []ConversationSummary{}
+	for rows.Next() {
+		var c ConversationSummary
+		if err := rows.Scan(&c.ID, &c.PostID, &c.BuyerGUID, &c.SellerGUID, &c.CreatedAt, &c.LastMessageAt,
+			&c.AdTitle, &c.OtherUsername, &c.LastMessage, &c.Unread); err != nil {
+			return nil, err
+		}
+		convs = append(convs, c)
+	}
+	return convs, rows.Err()
+}
+
+func (r *ConversationRepository) CountForUser(ctx context.Context, user uuid.UUID) (int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM conversations WHERE buyer_guid = $1 OR seller_guid = $1`, user).Scan(&total)
+	return total, err
+}
+
+// Messages returns a page of the conversation, newest message first.
+func (r *ConversationRepository) Messages(ctx context.Context, conversationID int64, limit, offset int) ([]domain.Message, error) {
+	query := `SELECT id, conversation_id, sender_guid, body, created_at FROM messages
+               WHERE conversation_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, conversationID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []domain.Message{}
+	for rows.Next() {
+		var m domain.Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderGUID, &m.Body, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *ConversationRepository) CountMessages(ctx context.Context, conversationID int64) (int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM messages WHERE conversation_id = $1`, conversationID).Scan(&total)
+	return total, err
+}
+
+// MarkRead marks every message of the conversation as read by the user.
+func (r *ConversationRepository) MarkRead(ctx context.Context, conversationID int64, user uuid.UUID) error {
+	query := `UPDATE conversations SET
+                buyer_last_read = CASE WHEN buyer_guid = $2 THEN latest.id ELSE buyer_last_read END,
+                seller_last_read = CASE WHEN seller_guid = $2 THEN latest.id ELSE seller_last_read END
+                FROM (SELECT COALESCE(MAX(id), 0) AS id FROM messages WHERE conversation_id = $1) latest
+               WHERE conversations.id = $1`
+
+	_, err := r.db.Exec(ctx, query, conversationID, user)
+
+	return err
+}
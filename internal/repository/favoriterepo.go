@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FavoriteRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFavoriteRepository(db *pgxpool.Pool) *FavoriteRepository {
+	return &FavoriteRepository{db: db}
+}
+
+// Add saves the ad to the user's favorites, adding it twice is a no-op.
+func (r *FavoriteRepository) Add(ctx context.Context, userGUID uuid.UUID, postID int64) error {
+	query := `INSERT INTO favorites (user_guid, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	_, err := r.db.Exec(ctx, query, userGUID, postID)
+
+	return err
+}
+
+// Remove drops the ad from the user's favorites, removing a missing one is a
+// no-op.
+func (r *FavoriteRepository) Remove(ctx context.Context, userGUID uuid.UUID, postID int64) error {
+	query := `DELETE FROM favorites WHERE user_guid = $1 AND post_id = $2`
+
+	_, err := r.db.Exec(ctx, query, userGUID, postID)
+
+	return err
+}
+
+// Favorited returns which of the ads are in the user's favorites.
+func (r *FavoriteRepository) Favorited(ctx context.Context, userGUID uuid.UUID, postIDs []int64) (map[int64]bool, error) {
+	favorited := make(map[int64]bool)
+	if len(postIDs) == 0 {
+		return favorited, nil
+	}
+
+	query := `SELECT post_id FROM favorites WHERE user_guid = $1 AND post_id = ANY($2)`
+	rows, err := r.db.Query(ctx, query, userGUID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		favorited[id] = true
+	}
+
+	return favorited, rows.Err()
+}
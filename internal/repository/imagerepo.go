@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+)
+
+type ImageRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewImageRepository(db *pgxpool.Pool) *ImageRepository {
+	return &ImageRepository{db: db}
+}
+
+func (r *ImageRepository) Create(ctx context.Context, image domain.Image) (*domain.Image, error) {
+	query := `INSERT INTO images (id, user_guid, content_type, size)
+              VALUES ($1, $2, $3, $4) RETURNING created_at`
+	err := r.db.QueryRow(ctx, query,
+		image.ID, image.UserGUID, image.ContentType, image.Size,
+	).Scan(&image.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *ImageRepository) Get(ctx context.Context, id uuid.UUID) (*domain.Image, error) {
+	query := `SELECT id, user_guid, content_type, size, created_at FROM images WHERE id = $1`
+
+	var image domain.Image
+	err := r.db.QueryRow(ctx, query, id).Scan(&image.ID, &image.UserGUID, &image.ContentType, &image.Size, &image.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
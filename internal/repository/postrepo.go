@@ -2,35 +2,252 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nerfthisdev/backend-test-task/internal/domain"
 )
 
+// ErrInvalidTransition is returned when an ad can't move to the requested
+// status from the one it is in.
+var ErrInvalidTransition = errors.New("invalid ad status transition")
+
 type PostRepository struct {
 	db *pgxpool.Pool
+	// lifetime is how long an ad stays published before it expires
+	lifetime time.Duration
 }
 
-func NewPostRepository(db *pgxpool.Pool) *PostRepository {
-	return &PostRepository{db: db}
+func NewPostRepository(db *pgxpool.Pool, lifetime time.Duration) *PostRepository {
+	return &PostRepository{db: db, lifetime: lifetime}
 }
 
 func (r *PostRepository) Create(ctx context.Context, post domain.Post) (*domain.Post, error) {
-	query := `INSERT INTO posts (user_guid, title, description, image_url, price)
-              VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	err := r.db.QueryRow(ctx, query,
-		post.UserGUID, post.Title, post.Description, post.ImageURL, post.Price,
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	if post.Status != domain.AdDraft {
+		post.Status = domain.AdPendingReview
+	}
+	post.ExpiresAt = time.Now().Add(r.lifetime)
+
+	query := `INSERT INTO posts (user_guid, category_id, title, description, image_url, price, status, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	err = tx.QueryRow(ctx, query,
+		post.UserGUID, post.CategoryID, post.Title, post.Description, post.ImageURL, post.Price, string(post.Status), post.ExpiresAt,
 	).Scan(&post.ID)
 	if err != nil {
 		return nil, err
 	}
+
+	if err := setImages(ctx, tx, post.ID, post.Images); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	return &post, nil
 }
 
+// Update saves the post fields. Its images are replaced only when
+// post.Images is not nil, the replaced images no other ad uses are deleted
+// and their ids returned so their stored files can be removed too. Editing a
+// published or rejected ad sends it back to review, the returned post carries
+// the resulting status.
+func (r *PostRepository) Update(ctx context.Context, post domain.Post) (*domain.Post, []uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rollback(ctx, tx)
+
+	query := `UPDATE posts SET category_id = $1, title = $2, description = $3, image_url = $4, price = $5,
+                status = CASE WHEN status IN ('published', 'rejected') THEN 'pending_review' ELSE status END,
+                rejection_reason = '',
+                status_changed_at = CASE WHEN status IN ('published', 'rejected') THEN NOW() ELSE status_changed_at END
+              WHERE id = $6 AND user_guid = $7 RETURNING id, status, expires_at`
+	err = tx.QueryRow(ctx, query,
+		post.CategoryID, post.Title, post.Description, post.ImageURL, post.Price, post.ID, post.UserGUID,
+	).Scan(&post.ID, &post.Status, &post.ExpiresAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var removed []uuid.UUID
+	if post.Images != nil {
+		rows, err := tx.Query(ctx, `DELETE FROM ad_images WHERE post_id = $1 RETURNING image_id`, post.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		replaced, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := setImages(ctx, tx, post.ID, post.Images); err != nil {
+			return nil, nil, err
+		}
+		if removed, err = deleteUnusedImages(ctx, tx, replaced); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return &post, removed, nil
+}
+
+// setImages attaches the images to the post in the given order, the first
+// one being the cover.
+func setImages(ctx context.Context, tx pgx.Tx, postID int64, images []uuid.UUID) error {
+	query := `INSERT INTO ad_images (post_id, image_id, position) VALUES ($1, $2, $3)`
+
+	for i, id := range images {
+		if _, err := tx.Exec(ctx, query, postID, id, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the ad together with the images no other ad uses and
+// returns the ids of those images so their stored files can be removed too.
+func (r *PostRepository) Delete(ctx context.Context, id int64) ([]uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	// ad_images rows go away with the post, remember what they pointed to
+	rows, err := tx.Query(ctx, `SELECT image_id FROM ad_images WHERE post_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	images, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM posts WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	removed, err := deleteUnusedImages(ctx, tx, images)
+	if err != nil {
+		return nil, err
+	}
+
+	return removed, tx.Commit(ctx)
+}
+
+// deleteUnusedImages deletes those of the images no ad references and
+// returns their ids.
+func deleteUnusedImages(ctx context.Context, tx pgx.Tx, images []uuid.UUID) ([]uuid.UUID, error) {
+	query := `DELETE FROM images i WHERE i.id = ANY($1)
+                AND NOT EXISTS (SELECT 1 FROM ad_images a WHERE a.image_id = i.id)
+              RETURNING i.id`
+	rows, err := tx.Query(ctx, query, images)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// statusParam converts statuses to a plain []string so pgx encodes them as a
+// text array.
+func statusParam(statuses []domain.AdStatus) []string {
+	out := make([]string, len(statuses))
+	for i, s := range statuses {
+		out[i] = string(s)
+	}
+	return out
+}
+
+// Transition moves the ad to status to if its current status allows it,
+// reason is kept only for rejections. Publishing gives the ad at least a full
+// lifetime so time spent in review doesn't count. It returns pgx.ErrNoRows
+// for unknown ads and ErrInvalidTransition when the current status forbids
+// the move.
+func (r *PostRepository) Transition(ctx context.Context, id int64, to domain.AdStatus, reason string) error {
+	if to != domain.AdRejected {
+		reason = ""
+	}
+
+	// the status check is part of the update so concurrent transitions
+	// can't both succeed
+	query := `UPDATE posts SET status = $2, rejection_reason = $3, status_changed_at = NOW(),
+                expires_at = CASE WHEN $2 = 'published' THEN GREATEST(expires_at, $5) ELSE expires_at END
+              WHERE id = $1 AND status = ANY($4)`
+	tag, err := r.db.Exec(ctx, query, id, string(to), reason, statusParam(domain.TransitionSources(to)), time.Now().Add(r.lifetime))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return pgx.ErrNoRows
+	}
+	return ErrInvalidTransition
+}
+
+// Renew gives the ad a full lifetime starting now. An archived ad is sent
+// back to review, drafts, rejected and sold ads can't be renewed and return
+// ErrInvalidTransition. It returns the status and expiry after renewal.
+func (r *PostRepository) Renew(ctx context.Context, id int64) (domain.AdStatus, time.Time, error) {
+	expiresAt := time.Now().Add(r.lifetime)
+	query := `UPDATE posts SET expires_at = $2,
+                status = CASE WHEN status = 'archived' THEN 'pending_review' ELSE status END,
+                status_changed_at = CASE WHEN status = 'archived' THEN NOW() ELSE status_changed_at END
+              WHERE id = $1 AND status IN ('published', 'pending_review', 'archived')
+              RETURNING status`
+
+	var status domain.AdStatus
+	err := r.db.QueryRow(ctx, query, id, expiresAt).Scan(&status)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return status, expiresAt, err
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return "", time.Time{}, err
+	}
+	if !exists {
+		return "", time.Time{}, pgx.ErrNoRows
+	}
+	return "", time.Time{}, ErrInvalidTransition
+}
+
+// ArchiveExpired archives every published ad past its expiry and returns how
+// many were archived.
+func (r *PostRepository) ArchiveExpired(ctx context.Context) (int64, error) {
+	query := `UPDATE posts SET status = 'archived', status_changed_at = NOW()
+              WHERE status = 'published' AND expires_at <= NOW()`
+
+	tag, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
 type ListOptions struct {
 	Page     int
 	PerPage  int
@@ -38,49 +255,144 @@ type ListOptions struct {
 	Order    string
 	MinPrice *float64
 	MaxPrice *float64
+	// CategoryID limits the list to the category and all of its descendants.
+	CategoryID *int64
+	// Query is a full-text search over titles and descriptions, both
+	// russian and english stemming is applied.
+	Query string
+	// After switches to keyset pagination, the page starts right after the
+	// given ad in the SortBy/Order ordering and Page is ignored. It is only
+	// supported for price and created_at sorting.
+	After *Cursor
+	// Statuses limits the list to ads in one of the statuses, nil means any.
+	Statuses []domain.AdStatus
+	// UserGUID limits the list to the ads of one author.
+	UserGUID *uuid.UUID
+	// IncludeExpired keeps ads past their expiry that are not archived yet.
+	IncludeExpired bool
+	// FavoritedBy limits the list to the favorites of a user.
+	FavoritedBy *uuid.UUID
+}
+
+// Cursor is the position of an ad in a price or created_at ordering.
+type Cursor struct {
+	ID        int64
+	Price     float64
+	CreatedAt time.Time
 }
 
 type Ad struct {
-	ID          int64
-	UserGUID    uuid.UUID
-	Username    string
-	Title       string
-	Description string
-	ImageURL    string
-	Price       float64
-	CreatedAt   time.Time
-}
-
-func (r *PostRepository) List(ctx context.Context, opt ListOptions) ([]Ad, error) {
-	query := `SELECT p.id, p.user_guid, u.username, p.title, p.description, p.image_url, p.price, p.created_at
-                FROM posts p JOIN users u ON p.user_guid = u.guid`
-	params := []any{}
+	ID              int64
+	UserGUID        uuid.UUID
+	Username        string
+	CategoryID      int64
+	Title           string
+	Description     string
+	ImageURL        string
+	Price           float64
+	CreatedAt       time.Time
+	Status          domain.AdStatus
+	ExpiresAt       time.Time
+	RejectionReason string
+	// Images is only loaded by Get, ordered with the cover first
+	Images []uuid.UUID
+}
+
+const searchQuery = `(websearch_to_tsquery('russian', $%[1]d) || websearch_to_tsquery('english', $%[1]d))`
+
+// filters builds the WHERE conditions shared by List and Count. searchIdx is
+// the placeholder of the search query or 0 when there is none.
+func (opt ListOptions) filters() (conds []string, params []any, searchIdx int) {
 	idx := 1
 
 	if opt.MinPrice != nil {
-		query += fmt.Sprintf(" WHERE p.price >= $%d", idx)
+		conds = append(conds, fmt.Sprintf("p.price >= $%d", idx))
 		params = append(params, *opt.MinPrice)
 		idx++
 	}
 	if opt.MaxPrice != nil {
-		if len(params) == 0 {
-			query += fmt.Sprintf(" WHERE p.price <= $%d", idx)
-		} else {
-			query += fmt.Sprintf(" AND p.price <= $%d", idx)
-		}
+		conds = append(conds, fmt.Sprintf("p.price <= $%d", idx))
 		params = append(params, *opt.MaxPrice)
 		idx++
 	}
+	if opt.CategoryID != nil {
+		conds = append(conds, fmt.Sprintf(`p.category_id IN (
+                WITH RECURSIVE tree AS (
+                    SELECT id FROM categories WHERE id = $%d
+                    UNION ALL
+                    SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+                ) SELECT id FROM tree)`, idx))
+		params = append(params, *opt.CategoryID)
+		idx++
+	}
+	if opt.Statuses != nil {
+		conds = append(conds, fmt.Sprintf("p.status = ANY($%d)", idx))
+		params = append(params, statusParam(opt.Statuses))
+		idx++
+	}
+	if opt.UserGUID != nil {
+		conds = append(conds, fmt.Sprintf("p.user_guid = $%d", idx))
+		params = append(params, *opt.UserGUID)
+		idx++
+	}
+	if opt.FavoritedBy != nil {
+		conds = append(conds, fmt.Sprintf("p.id IN (SELECT post_id FROM favorites WHERE user_guid = $%d)", idx))
+		params = append(params, *opt.FavoritedBy)
+		idx++
+	}
+	if !opt.IncludeExpired {
+		conds = append(conds, "p.expires_at > NOW()")
+	}
+	if opt.Query != "" {
+		searchIdx = idx
+		conds = append(conds, "p.search_vector @@ "+fmt.Sprintf(searchQuery, searchIdx))
+		params = append(params, opt.Query)
+	}
+
+	return conds, params, searchIdx
+}
+
+// List returns a page of ads and whether there are more ads after it.
+func (r *PostRepository) List(ctx context.Context, opt ListOptions) ([]Ad, bool, error) {
+	query := `SELECT p.id, p.user_guid, u.username, p.category_id, p.title, p.description, p.image_url, p.price, p.created_at,
+                     p.status, p.rejection_reason, p.expires_at
+                FROM posts p JOIN users u ON p.user_guid = u.guid`
+	conds, params, searchIdx := opt.filters()
+	idx := len(params) + 1
 
 	sortCol := "created_at"
-	if opt.SortBy == "price" {
-		sortCol = "price"
+	switch opt.SortBy {
+	case "price", "status_changed_at":
+		sortCol = opt.SortBy
 	}
 	order := "DESC"
 	if strings.ToUpper(opt.Order) == "ASC" {
 		order = "ASC"
 	}
-	query += fmt.Sprintf(" ORDER BY p.%s %s", sortCol, order)
+	relevance := opt.SortBy == "relevance" && searchIdx > 0
+
+	if opt.After != nil && (sortCol == "price" || sortCol == "created_at") && !relevance {
+		cmp := "<"
+		if order == "ASC" {
+			cmp = ">"
+		}
+		var value any = opt.After.CreatedAt
+		if sortCol == "price" {
+			value = opt.After.Price
+		}
+		conds = append(conds, fmt.Sprintf("(p.%s, p.id) %s ($%d, $%d)", sortCol, cmp, idx, idx+1))
+		params = append(params, value, opt.After.ID)
+		idx += 2
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	if relevance {
+		query += fmt.Sprintf(" ORDER BY ts_rank(p.search_vector, %s) DESC, p.id DESC", fmt.Sprintf(searchQuery, searchIdx))
+	} else {
+		query += fmt.Sprintf(" ORDER BY p.%s %s, p.id %s", sortCol, order, order)
+	}
 
 	limit := opt.PerPage
 	if limit <= 0 {
@@ -90,38 +402,83 @@ func (r *PostRepository) List(ctx context.Context, opt ListOptions) ([]Ad, error
 		opt.Page = 1
 	}
 	offset := (opt.Page - 1) * limit
+	if opt.After != nil {
+		offset = 0
+	}
+	// one extra row tells whether another page follows
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", idx, idx+1)
-	params = append(params, limit, offset)
+	params = append(params, limit+1, offset)
 
 	rows, err := r.db.Query(ctx, query, params...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
 	var ads []Ad
 	for rows.Next() {
 		var a Ad
-		if err := rows.Scan(&a.ID, &a.UserGUID, &a.Username, &a.Title, &a.Description, &a.ImageURL, &a.Price, &a.CreatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&a.ID, &a.UserGUID, &a.Username, &a.CategoryID, &a.Title, &a.Description, &a.ImageURL, &a.Price, &a.CreatedAt,
+			&a.Status, &a.RejectionReason, &a.ExpiresAt); err != nil {
+			return nil, false, err
 		}
 		ads = append(ads, a)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if len(ads) > limit {
+		return ads[:limit], true, nil
+	}
+	return ads, false, nil
+}
+
+// Count returns the number of ads matching the filters of opt, pagination
+// and sorting fields are ignored.
+func (r *PostRepository) Count(ctx context.Context, opt ListOptions) (int, error) {
+	query := `SELECT COUNT(*) FROM posts p`
+
+	conds, params, _ := opt.filters()
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
 	}
-	return ads, nil
+
+	var total int
+	err := r.db.QueryRow(ctx, query, params...).Scan(&total)
+
+	return total, err
 }
 
 func (r *PostRepository) Get(ctx context.Context, id int64) (*Ad, error) {
-	query := `SELECT p.id, p.user_guid, u.username, p.title, p.description, p.image_url, p.price, p.created_at
+	query := `SELECT p.id, p.user_guid, u.username, p.category_id, p.title, p.description, p.image_url, p.price, p.created_at,
+                     p.status, p.rejection_reason, p.expires_at
                FROM posts p JOIN users u ON p.user_guid = u.guid
                WHERE p.id = $1`
 
 	var a Ad
-	err := r.db.QueryRow(ctx, query, id).Scan(&a.ID, &a.UserGUID, &a.Username, &a.Title, &a.Description, &a.ImageURL, &a.Price, &a.CreatedAt)
+	err := r.db.QueryRow(ctx, query, id).Scan(&a.ID, &a.UserGUID, &a.Username, &a.CategoryID, &a.Title, &a.Description, &a.ImageURL, &a.Price, &a.CreatedAt,
+		&a.Status, &a.RejectionReason, &a.ExpiresAt)
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := r.db.Query(ctx, `SELECT image_id FROM ad_images WHERE post_id = $1 ORDER BY position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	a.Images = []uuid.UUID{}
+	for rows.Next() {
+		var imageID uuid.UUID
+		if err := rows.Scan(&imageID); err != nil {
+			return nil, err
+		}
+		a.Images = append(a.Images, imageID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return &a, nil
 }
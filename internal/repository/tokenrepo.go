@@ -1,7 +1,118 @@
 package repository
 
-import "github.com/jackc/pgx/v5/pgxpool"
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+)
 
 type TokenRepository struct {
 	db *pgxpool.Pool
 }
+
+func NewTokenRepository(db *pgxpool.Pool) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// StoreRefreshToken saves a new session, or replaces the token of an existing
+// one.
+func (r *TokenRepository) StoreRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (session_id, user_guid, token_hash, ip, user_agent, created_at, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              ON CONFLICT (session_id) DO UPDATE
+              SET token_hash = EXCLUDED.token_hash,
+                  ip = EXCLUDED.ip,
+                  user_agent = EXCLUDED.user_agent,
+                  expires_at = EXCLUDED.expires_at`
+
+	_, err := r.db.Exec(ctx, query,
+		token.SessionID, token.GUID, token.TokenHash, token.IP, token.UserAgent, token.CreatedAt, token.ExpiresAt,
+	)
+
+	return err
+}
+
+// RotateRefreshToken replaces the token of the session only if it still has
+// oldHash, so of two concurrent refreshes with the same token only one wins.
+// It reports false when the token was already rotated or the session is gone.
+func (r *TokenRepository) RotateRefreshToken(ctx context.Context, token domain.RefreshToken, oldHash string) (bool, error) {
+	query := `UPDATE refresh_tokens
+              SET token_hash = $2, ip = $3, user_agent = $4, expires_at = $5
+              WHERE session_id = $1 AND token_hash = $6`
+
+	tag, err := r.db.Exec(ctx, query,
+		token.SessionID, token.TokenHash, token.IP, token.UserAgent, token.ExpiresAt, oldHash,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *TokenRepository) GetRefreshToken(ctx context.Context, sessionID string) (domain.RefreshToken, error) {
+	query := `SELECT session_id, user_guid, token_hash, ip, user_agent, created_at, expires_at
+              FROM refresh_tokens WHERE session_id = $1`
+
+	var t domain.RefreshToken
+	err := r.db.QueryRow(ctx, query, sessionID).Scan(
+		&t.SessionID, &t.GUID, &t.TokenHash, &t.IP, &t.UserAgent, &t.CreatedAt, &t.ExpiresAt,
+	)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+
+	return t, nil
+}
+
+func (r *TokenRepository) GetUserRefreshTokens(ctx context.Context, guid uuid.UUID) ([]domain.RefreshToken, error) {
+	query := `SELECT session_id, user_guid, token_hash, ip, user_agent, created_at, expires_at
+              FROM refresh_tokens WHERE user_guid = $1 AND expires_at > NOW()
+              ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, guid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []domain.RefreshToken
+	for rows.Next() {
+		var t domain.RefreshToken
+		if err := rows.Scan(&t.SessionID, &t.GUID, &t.TokenHash, &t.IP, &t.UserAgent, &t.CreatedAt, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *TokenRepository) DeleteRefreshToken(ctx context.Context, sessionID string) error {
+	query := `DELETE FROM refresh_tokens WHERE session_id = $1`
+
+	_, err := r.db.Exec(ctx, query, sessionID)
+
+	return err
+}
+
+func (r *TokenRepository) DeleteUserRefreshTokens(ctx context.Context, guid uuid.UUID) error {
+	query := `DELETE FROM refresh_tokens WHERE user_guid = $1`
+
+	_, err := r.db.Exec(ctx, query, guid)
+
+	return err
+}
+
+func (r *TokenRepository) SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE session_id = $1 AND expires_at > NOW())`
+
+	var exists bool
+	err := r.db.QueryRow(ctx, query, sessionID).Scan(&exists)
+
+	return exists, err
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+)
+
+// rollback is deferred right after Begin. It is a no-op once the
+// transaction committed and logs a failed rollback through the request
+// logger, since nobody else would see it.
+func rollback(ctx context.Context, tx pgx.Tx) {
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		logging.FromContext(ctx).Warn("failed to roll back transaction", zap.Error(err))
+	}
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nerfthisdev/backend-test-task/internal/domain"
 )
@@ -17,42 +19,104 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user domain.User) error {
-	query := `INSERT INTO users (guid, username, password)
-	VALUES ($1, $2, $3)`
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+
+	query := `INSERT INTO users (guid, username, password, role)
+	VALUES ($1, $2, $3, $4)`
 
-	_, err := r.db.Exec(ctx, query, user.GUID, user.Username, user.Password)
+	_, err := r.db.Exec(ctx, query, user.GUID, user.Username, user.Password, user.Role)
 
 	return err
 }
 
-func (r *UserRepository) GetByGuid(ctx context.Context, guid uuid.UUID) (*domain.User, error) {
-	query := `SELECT guid, username, password FROM users WHERE guid = $1`
-
-	row := r.db.QueryRow(ctx, query, guid)
+const userColumns = `guid, username, password, role, locked_until, banned_at, ban_reason`
 
+func scanUser(row pgx.Row) (*domain.User, error) {
 	var user domain.User
-
-	err := row.Scan(&user.GUID, &user.Username, &user.Password)
+	err := row.Scan(&user.GUID, &user.Username, &user.Password, &user.Role,
+		&user.LockedUntil, &user.BannedAt, &user.BanReason)
 	if err != nil {
 		return nil, err
 	}
-
 	return &user, nil
 }
 
+func (r *UserRepository) GetByGuid(ctx context.Context, guid uuid.UUID) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE guid = $1`
+
+	return scanUser(r.db.QueryRow(ctx, query, guid))
+}
+
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
-	query := `SELECT guid, username, password FROM users WHERE username = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
 
-	row := r.db.QueryRow(ctx, query, username)
+	return scanUser(r.db.QueryRow(ctx, query, username))
+}
 
-	var user domain.User
+// List returns a page of users ordered by username.
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY username LIMIT $1 OFFSET $2`
 
-	err := row.Scan(&user.GUID, &user.Username, &user.Password)
+	rows, err := r.db.Query(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
 
-	return &user, nil
+func (r *UserRepository) Count(ctx context.Context) (int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&total)
+	return total, err
+}
+
+// SetRole changes the role of a user and returns the role they had before,
+// pgx.ErrNoRows means there is no such user.
+func (r *UserRepository) SetRole(ctx context.Context, guid uuid.UUID, role domain.Role) (domain.Role, error) {
+	// the joined row still holds the values from before the update
+	query := `UPDATE users u SET role = $2 FROM users old
+              WHERE u.guid = $1 AND old.guid = u.guid RETURNING old.role`
+
+	var previous domain.Role
+	err := r.db.QueryRow(ctx, query, guid, role).Scan(&previous)
+
+	return previous, err
+}
+
+// Ban marks the user as banned, pgx.ErrNoRows means there is no such user.
+func (r *UserRepository) Ban(ctx context.Context, guid uuid.UUID, reason string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE users SET banned_at = NOW(), ban_reason = $2 WHERE guid = $1`, guid, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Unban lifts a ban, pgx.ErrNoRows means there is no such user.
+func (r *UserRepository) Unban(ctx context.Context, guid uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `UPDATE users SET banned_at = NULL, ban_reason = '' WHERE guid = $1`, guid)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
 }
 
 func (r *UserRepository) Delete(ctx context.Context, user domain.User) error {
@@ -62,3 +126,96 @@ func (r *UserRepository) Delete(ctx context.Context, user domain.User) error {
 
 	return err
 }
+
+// LockoutPolicy locks an account for Base after Threshold consecutive failed
+// logins, doubling the duration with every further lockout up to Max. A zero
+// Threshold only records attempts.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// attemptRetention is how long failed login attempts are kept.
+const attemptRetention = 30 * 24 * time.Hour
+
+// RecordFailedLogin stores the attempt and bumps the failure counter, locking
+// the account once it reaches the policy threshold. It returns the lock
+// expiry when this attempt locked the account.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, attempt domain.LoginAttempt, policy LockoutPolicy) (*time.Time, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO login_attempts (user_guid, ip, user_agent) VALUES ($1, $2, $3)`,
+		attempt.UserGUID, attempt.IP, attempt.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM login_attempts WHERE user_guid = $1 AND created_at < $2`,
+		attempt.UserGUID, time.Now().Add(-attemptRetention))
+	if err != nil {
+		return nil, err
+	}
+
+	// SET expressions all see the old row, so lockouts is the count before
+	// this one when computing the duration
+	query := `UPDATE users SET
+		failed_logins = CASE WHEN $2 > 0 AND failed_logins + 1 >= $2 THEN 0 ELSE failed_logins + 1 END,
+		lockouts = CASE WHEN $2 > 0 AND failed_logins + 1 >= $2 THEN lockouts + 1 ELSE lockouts END,
+		locked_until = CASE WHEN $2 > 0 AND failed_logins + 1 >= $2
+			THEN NOW() + make_interval(secs => LEAST($3 * power(2, LEAST(lockouts, 30)), $4))
+			ELSE locked_until END
+	WHERE guid = $1
+	RETURNING $2 > 0 AND failed_logins = 0, locked_until`
+
+	var locked bool
+	var lockedUntil *time.Time
+	err = tx.QueryRow(ctx, query, attempt.UserGUID, policy.Threshold, policy.Base.Seconds(), policy.Max.Seconds()).
+		Scan(&locked, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, nil
+	}
+	return lockedUntil, nil
+}
+
+// ResetFailedLogins clears the failure counters after a successful login.
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, guid uuid.UUID) error {
+	query := `UPDATE users SET failed_logins = 0, lockouts = 0, locked_until = NULL
+	WHERE guid = $1 AND (failed_logins > 0 OR lockouts > 0 OR locked_until IS NOT NULL)`
+
+	_, err := r.db.Exec(ctx, query, guid)
+
+	return err
+}
+
+// ListFailedLogins returns the most recent failed logins of a user.
+func (r *UserRepository) ListFailedLogins(ctx context.Context, guid uuid.UUID, limit int) ([]domain.LoginAttempt, error) {
+	query := `SELECT id, user_guid, ip, user_agent, created_at FROM login_attempts
+	WHERE user_guid = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, guid, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []domain.LoginAttempt{}
+	for rows.Next() {
+		var a domain.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.UserGUID, &a.IP, &a.UserAgent, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
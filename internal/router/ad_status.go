@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+// ownerStatuses are the statuses an author may move their own ad to, the
+// rest are reserved for moderators.
+var ownerStatuses = map[domain.AdStatus]bool{
+	domain.AdDraft:         true,
+	domain.AdPendingReview: true,
+	domain.AdArchived:      true,
+	domain.AdSold:          true,
+}
+
+type SetAdStatusHandler struct {
+	posts *repository.PostRepository
+}
+
+func NewSetAdStatusHandler(posts *repository.PostRepository) *SetAdStatusHandler {
+	return &SetAdStatusHandler{posts: posts}
+}
+
+type setAdStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+}
+
+type adStatusResponse struct {
+	ID              int64  `json:"id"`
+	Status          string `json:"status"`
+	RejectionReason string `json:"rejection_reason,omitempty"`
+}
+
+// ServeHTTP changes the status of an ad owned by the current user.
+// @Summary Change ad status
+// @Tags ads
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param input body setAdStatusRequest true "draft, pending_review, archived or sold"
+// @Success 200 {object} adStatusResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id}/status [put]
+func (h *SetAdStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	var req setAdStatusRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	status := domain.AdStatus(req.Status)
+	if !ownerStatuses[status] {
+		problem.Validation(w, []problem.FieldError{{Field: "status", Code: problem.FieldInvalid, Message: "status must be one of draft, pending_review, archived, sold"}})
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+	if ad.UserGUID != guid {
+		problem.Forbidden(w)
+		return
+	}
+
+	if !transition(w, r, h.posts, id, status, "") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adStatusResponse{ID: id, Status: string(status)})
+}
+
+// transition moves the ad to the status and writes the error response if
+// that fails.
+func transition(w http.ResponseWriter, r *http.Request, posts *repository.PostRepository, id int64, to domain.AdStatus, reason string) bool {
+	err := posts.Transition(r.Context(), id, to, reason)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, pgx.ErrNoRows):
+		problem.NotFound(w)
+	case errors.Is(err, repository.ErrInvalidTransition):
+		problem.Write(w, http.StatusConflict, problem.CodeInvalidTransition, "ad can't be moved to "+string(to)+" from its current status")
+	default:
+		internalError(w, r, "failed to change ad status", err)
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+)
+
+type RemoveAdHandler struct {
+	posts   *repository.PostRepository
+	storage storage.Storage
+}
+
+func NewRemoveAdHandler(posts *repository.PostRepository, store storage.Storage) *RemoveAdHandler {
+	return &RemoveAdHandler{posts: posts, storage: store}
+}
+
+// ServeHTTP removes any ad regardless of its owner.
+// @Summary Remove ad
+// @Tags admin
+// @Param id path int true "Ad ID"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /admin/ads/{id} [delete]
+func (h *RemoveAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+
+	images, err := h.posts.Delete(r.Context(), id)
+	if err != nil {
+		internalError(w, r, "failed to delete ad", err)
+		return
+	}
+	deleteImages(r.Context(), h.storage, images)
+	logging.FromContext(r.Context()).Info("ad removed by moderator",
+		zap.Int64("ad_id", ad.ID), zap.String("owner", ad.UserGUID.String()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
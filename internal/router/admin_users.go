@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+type userResponse struct {
+	GUID        uuid.UUID   `json:"guid"`
+	Username    string      `json:"username"`
+	Role        domain.Role `json:"role"`
+	LockedUntil *time.Time  `json:"locked_until,omitempty"`
+	BannedAt    *time.Time  `json:"banned_at,omitempty"`
+	BanReason   string      `json:"ban_reason,omitempty"`
+}
+
+type listUsersResponse struct {
+	Items   []userResponse  `json:"items"`
+	Page    int             `json:"page"`
+	PerPage int             `json:"per_page"`
+	Total   int             `json:"total"`
+	Links   paginationLinks `json:"links"`
+}
+
+type ListUsersHandler struct {
+	users *repository.UserRepository
+}
+
+func NewListUsersHandler(users *repository.UserRepository) *ListUsersHandler {
+	return &ListUsersHandler{users: users}
+}
+
+// ServeHTTP returns a page of users ordered by username.
+// @Summary List users
+// @Tags admin
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page, at most 100"
+// @Success 200 {object} listUsersResponse
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Security XAuthToken
+// @Router /admin/users [get]
+func (h *ListUsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, perPage := pageParams(r, 20)
+
+	users, err := h.users.List(r.Context(), perPage, (page-1)*perPage)
+	if err != nil {
+		internalError(w, r, "failed to list users", err)
+		return
+	}
+	total, err := h.users.Count(r.Context())
+	if err != nil {
+		internalError(w, r, "failed to count users", err)
+		return
+	}
+
+	resp := listUsersResponse{Items: make([]userResponse, 0, len(users)), Page: page, PerPage: perPage, Total: total}
+	for _, u := range users {
+		resp.Items = append(resp.Items, userResponse{
+			GUID:        u.GUID,
+			Username:    u.Username,
+			Role:        u.Role,
+			LockedUntil: u.LockedUntil,
+			BannedAt:    u.BannedAt,
+			BanReason:   u.BanReason,
+		})
+	}
+	resp.Links = pageLinks(r, page, page*perPage < total)
+	setLinkHeader(w, resp.Links)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type BanUserHandler struct {
+	users    *repository.UserRepository
+	sessions *auth.SessionManager
+}
+
+func NewBanUserHandler(users *repository.UserRepository, sessions *auth.SessionManager) *BanUserHandler {
+	return &BanUserHandler{users: users, sessions: sessions}
+}
+
+type banRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ServeHTTP bans a user and ends all their sessions. Admins can't be banned.
+// @Summary Ban user
+// @Tags admin
+// @Accept json
+// @Param guid path string true "User GUID"
+// @Param data body banRequest true "ban reason"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /admin/users/{guid}/ban [post]
+func (h *BanUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		problem.BadRequest(w, "user guid must be a UUID")
+		return
+	}
+
+	var req banRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.users.GetByGuid(r.Context(), target)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get user", err)
+		return
+	}
+	if user.Role == domain.RoleAdmin {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "admins can't be banned")
+		return
+	}
+
+	if err := h.users.Ban(r.Context(), target, req.Reason); err != nil {
+		internalError(w, r, "failed to ban user", err)
+		return
+	}
+	if err := h.sessions.RevokeAll(r.Context(), target); err != nil {
+		internalError(w, r, "failed to end sessions of banned user", err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("user banned", zap.String("target", target.String()), zap.String("reason", req.Reason))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type UnbanUserHandler struct {
+	users *repository.UserRepository
+}
+
+func NewUnbanUserHandler(users *repository.UserRepository) *UnbanUserHandler {
+	return &UnbanUserHandler{users: users}
+}
+
+// ServeHTTP lifts the ban of a user.
+// @Summary Unban user
+// @Tags admin
+// @Param guid path string true "User GUID"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /admin/users/{guid}/ban [delete]
+func (h *UnbanUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		problem.BadRequest(w, "user guid must be a UUID")
+		return
+	}
+
+	if err := h.users.Unban(r.Context(), target); errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to unban user", err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("user unbanned", zap.String("target", target.String()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type SetUserRoleHandler struct {
+	users    *repository.UserRepository
+	sessions *auth.SessionManager
+}
+
+func NewSetUserRoleHandler(users *repository.UserRepository, sessions *auth.SessionManager) *SetUserRoleHandler {
+	return &SetUserRoleHandler{users: users, sessions: sessions}
+}
+
+type setRoleRequest struct {
+	Role domain.Role `json:"role" validate:"required"`
+}
+
+// ServeHTTP changes the role of a user. A promotion takes effect on their
+// next token refresh, a demotion ends all their sessions right away since
+// access tokens carry the old role. Admins can't change their own role so
+// the last one can't lock everybody out.
+// @Summary Set user role
+// @Tags admin
+// @Accept json
+// @Param guid path string true "User GUID"
+// @Param data body setRoleRequest true "new role, one of user, moderator, admin"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /admin/users/{guid}/role [put]
+func (h *SetUserRoleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		problem.BadRequest(w, "user guid must be a UUID")
+		return
+	}
+
+	var req setRoleRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !req.Role.Valid() {
+		problem.Validation(w, []problem.FieldError{
+			{Field: "role", Code: problem.FieldInvalid, Message: "role must be one of user, moderator, admin"},
+		})
+		return
+	}
+
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if userID == target.String() {
+		problem.Write(w, http.StatusForbidden, problem.CodeForbidden, "admins can't change their own role")
+		return
+	}
+
+	previous, err := h.users.SetRole(r.Context(), target, req.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to set role", err)
+		return
+	}
+	if !req.Role.AtLeast(previous) {
+		if err := h.sessions.RevokeAll(r.Context(), target); err != nil {
+			internalError(w, r, "failed to end sessions of demoted user", err)
+			return
+		}
+	}
+	logging.FromContext(r.Context()).Info("user role changed", zap.String("target", target.String()), zap.String("role", string(req.Role)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+type sendMessageRequest struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+type messageResponse struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversation_id"`
+	SenderGUID     uuid.UUID `json:"sender_guid"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+	IsMine         bool      `json:"is_mine"`
+}
+
+func newMessageResponse(m domain.Message, current uuid.UUID) messageResponse {
+	return messageResponse{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderGUID:     m.SenderGUID,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+		IsMine:         m.SenderGUID == current,
+	}
+}
+
+type conversationResponse struct {
+	ID            int64     `json:"id"`
+	AdID          int64     `json:"ad_id"`
+	AdTitle       string    `json:"ad_title"`
+	Role          string    `json:"role"`
+	OtherLogin    string    `json:"other_login"`
+	LastMessage   string    `json:"last_message"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	Unread        int       `json:"unread"`
+}
+
+type listConversationsResponse struct {
+	Items   []conversationResponse `json:"items"`
+	Page    int                    `json:"page"`
+	PerPage int                    `json:"per_page"`
+	Total   int                    `json:"total"`
+	Links   paginationLinks        `json:"links"`
+}
+
+type listMessagesResponse struct {
+	Items   []messageResponse `json:"items"`
+	Page    int               `json:"page"`
+	PerPage int               `json:"per_page"`
+	Total   int               `json:"total"`
+	Links   paginationLinks   `json:"links"`
+}
+
+// participantConversation loads the conversation from the path and checks
+// that the current user takes part in it, writing the error response if not.
+func participantConversation(w http.ResponseWriter, r *http.Request, convs *repository.ConversationRepository) (*domain.Conversation, uuid.UUID, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "conversation id must be a positive integer")
+		return nil, uuid.Nil, false
+	}
+
+	guid, ok := currentUser(r)
+	if !ok {
+		problem.Unauthorized(w)
+		return nil, uuid.Nil, false
+	}
+
+	conv, err := convs.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return nil, uuid.Nil, false
+	} else if err != nil {
+		internalError(w, r, "failed to get conversation", err)
+		return nil, uuid.Nil, false
+	}
+	if !conv.HasParticipant(guid) {
+		problem.Forbidden(w)
+		return nil, uuid.Nil, false
+	}
+
+	return conv, guid, true
+}
+
+type SendAdMessageHandler struct {
+	posts         *repository.PostRepository
+	conversations *repository.ConversationRepository
+}
+
+func NewSendAdMessageHandler(posts *repository.PostRepository, conversations *repository.ConversationRepository) *SendAdMessageHandler {
+	return &SendAdMessageHandler{posts: posts, conversations: conversations}
+}
+
+// ServeHTTP sends a message to the author of a published ad, opening a
+// conversation on the first one.
+// @Summary Message ad author
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param input body sendMessageRequest true "message"
+// @Success 201 {object} messageResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id}/messages [post]
+func (h *SendAdMessageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	guid, ok := currentUser(r)
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+
+	var req sendMessageRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+	if ad.Status != domain.AdPublished || !ad.ExpiresAt.After(time.Now()) {
+		problem.NotFound(w)
+		return
+	}
+	if ad.UserGUID == guid {
+		problem.BadRequest(w, "you can't message yourself about your own ad")
+		return
+	}
+
+	msg, err := h.conversations.Send(r.Context(), id, guid, ad.UserGUID, req.Body)
+	if err != nil {
+		internalError(w, r, "failed to send message", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newMessageResponse(*msg, guid))
+}
+
+type ListConversationsHandler struct {
+	conversations *repository.ConversationRepository
+}
+
+func NewListConversationsHandler(conversations *repository.ConversationRepository) *ListConversationsHandler {
+	return &ListConversationsHandler{conversations: conversations}
+}
+
+// ServeHTTP returns the conversations of the current user, most recently
+// active first, with unread message counts.
+// @Summary List conversations
+// @Tags messages
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page, at most 100"
+// @Success 200 {object} listConversationsResponse
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/conversations [get]
+func (h *ListConversationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	guid, ok := currentUser(r)
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	page, perPage := pageParams(r, 20)
+
+	convs, err := h.conversations.ListForUser(r.Context(), guid, perPage, (page-1)*perPage)
+	if err != nil {
+		internalError(w, r, "failed to list conversations", err)
+		return
+	}
+	total, err := h.conversations.CountForUser(r.Context(), guid)
+	if err != nil {
+		internalError(w, r, "failed to count conversations", err)
+		return
+	}
+
+	resp := listConversationsResponse{Items: make([]conversationResponse, 0, len(convs)), Page: page, PerPage: perPage, Total: total}
+	for _, c := range convs {
+		role := "buyer"
+		if c.SellerGUID == guid {
+			role = "seller"
+		}
+		resp.Items = append(resp.Items, conversationResponse{
+			ID:            c.ID,
+			AdID:          c.PostID,
+			AdTitle:       c.AdTitle,
+			Role:          role,
+			OtherLogin:    c.OtherUsername,
+			LastMessage:   c.LastMessage,
+			LastMessageAt: c.LastMessageAt,
+			Unread:        c.Unread,
+		})
+	}
+	resp.Links = pageLinks(r, page, page*perPage < total)
+	setLinkHeader(w, resp.Links)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type ListMessagesHandler struct {
+	conversations *repository.ConversationRepository
+}
+
+func NewListMessagesHandler(conversations *repository.ConversationRepository) *ListMessagesHandler {
+	return &ListMessagesHandler{conversations: conversations}
+}
+
+// ServeHTTP returns the messages of a conversation, newest first. Only its
+// two participants may read it.
+// @Summary List messages
+// @Tags messages
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param page query int false "page"
+// @Param per_page query int false "per page, at most 100"
+// @Success 200 {object} listMessagesResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/conversations/{id}/messages [get]
+func (h *ListMessagesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conv, guid, ok := participantConversation(w, r, h.conversations)
+	if !ok {
+		return
+	}
+	page, perPage := pageParams(r, 50)
+
+	messages, err := h.conversations.Messages(r.Context(), conv.ID, perPage, (page-1)*perPage)
+	if err != nil {
+		internalError(w, r, "failed to list messages", err)
+		return
+	}
+	total, err := h.conversations.CountMessages(r.Context(), conv.ID)
+	if err != nil {
+		internalError(w, r, "failed to count messages", err)
+		return
+	}
+
+	resp := listMessagesResponse{Items: make([]messageResponse, 0, len(messages)), Page: page, PerPage: perPage, Total: total}
+	for _, m := range messages {
+		resp.Items = append(resp.Items, newMessageResponse(m, guid))
+	}
+	resp.Links = pageLinks(r, page, page*perPage < total)
+	setLinkHeader(w, resp.Links)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type ReplyHandler struct {
+	conversations *repository.ConversationRepository
+}
+
+func NewReplyHandler(conversations *repository.ConversationRepository) *ReplyHandler {
+	return &ReplyHandler{conversations: conversations}
+}
+
+// ServeHTTP sends a message to an existing conversation.
+// @Summary Reply in conversation
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param input body sendMessageRequest true "message"
+// @Success 201 {object} messageResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/conversations/{id}/messages [post]
+func (h *ReplyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conv, guid, ok := participantConversation(w, r, h.conversations)
+	if !ok {
+		return
+	}
+
+	var req sendMessageRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	msg, err := h.conversations.Reply(r.Context(), conv.ID, guid, req.Body)
+	if err != nil {
+		internalError(w, r, "failed to send message", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newMessageResponse(*msg, guid))
+}
+
+type MarkConversationReadHandler struct {
+	conversations *repository.ConversationRepository
+}
+
+func NewMarkConversationReadHandler(conversations *repository.ConversationRepository) *MarkConversationReadHandler {
+	return &MarkConversationReadHandler{conversations: conversations}
+}
+
+// ServeHTTP marks every message of the conversation as read by the current
+// user.
+// @Summary Mark conversation as read
+// @Tags messages
+// @Param id path int true "Conversation ID"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/conversations/{id}/read [post]
+func (h *MarkConversationReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conv, guid, ok := participantConversation(w, r, h.conversations)
+	if !ok {
+		return
+	}
+
+	if err := h.conversations.MarkRead(r.Context(), conv.ID, guid); err != nil {
+		internalError(w, r, "failed to mark conversation as read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
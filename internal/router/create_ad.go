@@ -3,89 +3,105 @@ package server
 import (
 	"encoding/json"
 	"net/http"
-	"net/url"
 
 	"github.com/google/uuid"
 
 	"github.com/nerfthisdev/backend-test-task/internal/auth"
 	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/imaging"
+	"github.com/nerfthisdev/backend-test-task/internal/metrics"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
 )
 
 type CreateAdHandler struct {
-	posts *repository.PostRepository
+	posts      *repository.PostRepository
+	categories *repository.CategoryRepository
+	images     *repository.ImageRepository
 }
 
-func NewCreateAdHandler(posts *repository.PostRepository) *CreateAdHandler {
-	return &CreateAdHandler{posts: posts}
+func NewCreateAdHandler(posts *repository.PostRepository, categories *repository.CategoryRepository, images *repository.ImageRepository) *CreateAdHandler {
+	return &CreateAdHandler{posts: posts, categories: categories, images: images}
 }
 
 type createAdRequest struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	ImageURL    string  `json:"image_url"`
-	Price       float64 `json:"price"`
+	CategoryID  int64       `json:"category_id" validate:"required,min=1"`
+	Title       string      `json:"title" validate:"required,max=100"`
+	Description string      `json:"description" validate:"required,max=500"`
+	Images      []uuid.UUID `json:"images" validate:"required,max=10,unique"`
+	Price       float64     `json:"price" validate:"min=0"`
+	// Draft keeps the ad private instead of submitting it for review
+	Draft bool `json:"draft"`
 }
 
-// ServeHTTP creates a new ad.
+// ServeHTTP creates a new ad and submits it for review, or saves it as a draft.
 // @Summary Create ad
 // @Tags ads
 // @Accept json
 // @Produce json
 // @Param data body createAdRequest true "ad info"
 // @Success 200 {object} domain.Post
-// @Failure 400 {string} string
-// @Failure 401 {string} string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
 // @Security XAuthToken
 // @Router /ads [post]
 func (h *CreateAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var req createAdRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if len(req.Title) == 0 || len(req.Title) > 100 {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	if exists, err := h.categories.Exists(r.Context(), req.CategoryID); err != nil {
+		internalError(w, r, "failed to check category", err)
 		return
-	}
-	if len(req.Description) == 0 || len(req.Description) > 500 {
-		http.Error(w, "invalid request", http.StatusBadRequest)
-		return
-	}
-	if req.Price < 0 {
-		http.Error(w, "invalid request", http.StatusBadRequest)
-		return
-	}
-	if _, err := url.ParseRequestURI(req.ImageURL); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	} else if !exists {
+		problem.Validation(w, []problem.FieldError{
+			{Field: "category_id", Code: problem.FieldNotFound, Message: "category does not exist"},
+		})
 		return
 	}
 
 	userID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		problem.Unauthorized(w)
 		return
 	}
 	guid, err := uuid.Parse(userID)
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		problem.Unauthorized(w)
+		return
+	}
+
+	if owned, err := areOwnedImages(r.Context(), h.images, req.Images, guid); err != nil {
+		internalError(w, r, "failed to check image", err)
+		return
+	} else if !owned {
+		problem.Validation(w, []problem.FieldError{
+			{Field: "images", Code: problem.FieldNotFound, Message: "images must be uploaded by you"},
+		})
 		return
 	}
 
 	post := domain.Post{
 		UserGUID:    guid,
+		CategoryID:  req.CategoryID,
 		Title:       req.Title,
 		Description: req.Description,
-		ImageURL:    req.ImageURL,
+		Images:      req.Images,
+		ImageURL:    thumbnailURL(req.Images[0], imaging.ThumbnailMedium),
 		Price:       req.Price,
+		Status:      domain.AdPendingReview,
+	}
+	if req.Draft {
+		post.Status = domain.AdDraft
 	}
 
 	created, err := h.posts.Create(r.Context(), post)
 	if err != nil {
-		http.Error(w, "failed to create ad", http.StatusInternalServerError)
+		internalError(w, r, "failed to create ad", err)
 		return
 	}
+	metrics.AdsCreated.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
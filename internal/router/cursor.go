@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// adCursor is the opaque keyset pagination cursor handed to clients. It
+// remembers the ordering it was issued for so it cannot be replayed against
+// a different one.
+type adCursor struct {
+	SortBy    string    `json:"s"`
+	Order     string    `json:"o"`
+	ID        int64     `json:"id"`
+	Price     float64   `json:"p"`
+	CreatedAt time.Time `json:"t"`
+}
+
+func encodeCursor(sortBy, order string, ad repository.Ad) string {
+	raw, _ := json.Marshal(adCursor{
+		SortBy:    sortBy,
+		Order:     order,
+		ID:        ad.ID,
+		Price:     ad.Price,
+		CreatedAt: ad.CreatedAt,
+	})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded string) (adCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return adCursor{}, errInvalidCursor
+	}
+
+	var c adCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return adCursor{}, errInvalidCursor
+	}
+	if c.ID <= 0 || (c.SortBy != "price" && c.SortBy != "created_at") || (c.Order != "asc" && c.Order != "desc") {
+		return adCursor{}, errInvalidCursor
+	}
+
+	return c, nil
+}
+
+func (c adCursor) position() *repository.Cursor {
+	return &repository.Cursor{ID: c.ID, Price: c.Price, CreatedAt: c.CreatedAt}
+}
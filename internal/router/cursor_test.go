@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)
+	ad := repository.Ad{ID: 42, Price: 199.99, CreatedAt: createdAt}
+
+	tests := []struct {
+		sortBy string
+		order  string
+	}{
+		{"price", "asc"},
+		{"price", "desc"},
+		{"created_at", "asc"},
+		{"created_at", "desc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy+"_"+tt.order, func(t *testing.T) {
+			c, err := decodeCursor(encodeCursor(tt.sortBy, tt.order, ad))
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if c.SortBy != tt.sortBy || c.Order != tt.order {
+				t.Errorf("ordering = %s %s, want %s %s", c.SortBy, c.Order, tt.sortBy, tt.order)
+			}
+
+			pos := c.position()
+			if pos.ID != ad.ID || pos.Price != ad.Price || !pos.CreatedAt.Equal(ad.CreatedAt) {
+				t.Errorf("position = %+v, want id %d price %v created_at %v", *pos, ad.ID, ad.Price, ad.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"not base64", "%%%"},
+		{"not json", encode("cursor")},
+		{"missing id", encode(`{"s":"price","o":"asc"}`)},
+		{"negative id", encode(`{"s":"price","o":"asc","id":-1}`)},
+		{"relevance sorting", encode(`{"s":"relevance","o":"desc","id":1}`)},
+		{"unknown sorting", encode(`{"s":"title","o":"asc","id":1}`)},
+		{"unknown order", encode(`{"s":"price","o":"up","id":1}`)},
+		{"wrong field type", encode(`{"s":"price","o":"asc","id":"1"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.encoded); !errors.Is(err, errInvalidCursor) {
+				t.Errorf("decodeCursor(%q) error = %v, want errInvalidCursor", tt.encoded, err)
+			}
+		})
+	}
+}
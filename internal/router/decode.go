@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/validation"
+)
+
+// maxBodySize caps JSON request bodies, uploads have their own limit.
+const maxBodySize = 1 << 20
+
+// requestDTOs are the bodies read with decodeJSON, NewRouter checks their
+// `validate` tags so a typo fails at startup.
+var requestDTOs = []any{
+	registerRequest{},
+	loginRequest{},
+	refreshRequest{},
+	createAdRequest{},
+	updateAdRequest{},
+	setAdStatusRequest{},
+	rejectAdRequest{},
+	banRequest{},
+	setRoleRequest{},
+	sendMessageRequest{},
+}
+
+// decodeJSON reads a single JSON object from the body into dst, rejecting
+// unknown fields and oversized bodies, then validates dst against its
+// `validate` tags. It writes the problem response and returns false on any
+// failure.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("body must contain a single JSON object")
+	}
+	if err != nil {
+		writeDecodeError(w, err)
+		return false
+	}
+
+	if errs := validation.Struct(dst); len(errs) > 0 {
+		problem.Validation(w, errs)
+		return false
+	}
+	return true
+}
+
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &tooLarge):
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge,
+			fmt.Sprintf("body must not exceed %d bytes", tooLarge.Limit))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		problem.Validation(w, []problem.FieldError{{
+			Field:   typeErr.Field,
+			Code:    problem.FieldInvalid,
+			Message: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type),
+		}})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		problem.Validation(w, []problem.FieldError{{
+			Field:   field,
+			Code:    problem.FieldUnknown,
+			Message: fmt.Sprintf("unknown field %s", field),
+		}})
+	default:
+		problem.BadRequest(w, "malformed JSON body")
+	}
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/nerfthisdev/backend-test-task/internal/validation"
+)
+
+// TestRequestDTOTags catches a malformed validate tag before NewRouter does.
+func TestRequestDTOTags(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatal(r)
+		}
+	}()
+	validation.MustRegister(requestDTOs...)
+}
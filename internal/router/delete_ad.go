@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+)
+
+type DeleteAdHandler struct {
+	posts   *repository.PostRepository
+	storage storage.Storage
+}
+
+func NewDeleteAdHandler(posts *repository.PostRepository, store storage.Storage) *DeleteAdHandler {
+	return &DeleteAdHandler{posts: posts, storage: store}
+}
+
+// ServeHTTP removes an ad owned by the current user.
+// @Summary Delete ad
+// @Tags ads
+// @Param id path int true "Ad ID"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id} [delete]
+func (h *DeleteAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+	if ad.UserGUID != guid {
+		problem.Forbidden(w)
+		return
+	}
+
+	images, err := h.posts.Delete(r.Context(), id)
+	if err != nil {
+		internalError(w, r, "failed to delete ad", err)
+		return
+	}
+	deleteImages(r.Context(), h.storage, images)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+// internalError logs err through the request logger and answers 500 with a
+// detail that is safe to show to clients.
+func internalError(w http.ResponseWriter, r *http.Request, detail string, err error) {
+	logging.FromContext(r.Context()).Error(detail, zap.Error(err))
+	problem.Internal(w, detail)
+}
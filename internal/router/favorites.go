@@ -0,0 +1,152 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+type AddFavoriteHandler struct {
+	posts     *repository.PostRepository
+	favorites *repository.FavoriteRepository
+}
+
+func NewAddFavoriteHandler(posts *repository.PostRepository, favorites *repository.FavoriteRepository) *AddFavoriteHandler {
+	return &AddFavoriteHandler{posts: posts, favorites: favorites}
+}
+
+// ServeHTTP adds a published ad to the favorites of the current user.
+// @Summary Add ad to favorites
+// @Tags favorites
+// @Param id path int true "Ad ID"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id}/favorite [put]
+func (h *AddFavoriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	guid, ok := currentUser(r)
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+	// only ads the public can see may be saved
+	if ad.Status != domain.AdPublished || !ad.ExpiresAt.After(time.Now()) {
+		problem.NotFound(w)
+		return
+	}
+
+	if err := h.favorites.Add(r.Context(), guid, id); err != nil {
+		internalError(w, r, "failed to add favorite", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type RemoveFavoriteHandler struct {
+	favorites *repository.FavoriteRepository
+}
+
+func NewRemoveFavoriteHandler(favorites *repository.FavoriteRepository) *RemoveFavoriteHandler {
+	return &RemoveFavoriteHandler{favorites: favorites}
+}
+
+// ServeHTTP removes an ad from the favorites of the current user.
+// @Summary Remove ad from favorites
+// @Tags favorites
+// @Param id path int true "Ad ID"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id}/favorite [delete]
+func (h *RemoveFavoriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	guid, ok := currentUser(r)
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+
+	if err := h.favorites.Remove(r.Context(), guid, id); err != nil {
+		internalError(w, r, "failed to remove favorite", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ListFavoritesHandler struct {
+	posts     *repository.PostRepository
+	favorites *repository.FavoriteRepository
+}
+
+func NewListFavoritesHandler(posts *repository.PostRepository, favorites *repository.FavoriteRepository) *ListFavoritesHandler {
+	return &ListFavoritesHandler{posts: posts, favorites: favorites}
+}
+
+// ServeHTTP returns the favorite ads of the current user that are still
+// published, with the same filters and pagination as the feed.
+// @Summary List favorite ads
+// @Tags favorites
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page, at most 100"
+// @Param q query string false "full-text search over title and description"
+// @Param sort_by query string false "sort field, relevance requires q" Enums(price, created_at, relevance)
+// @Param order query string false "order" Enums(asc, desc)
+// @Param min_price query number false "min price"
+// @Param max_price query number false "max price"
+// @Param category query int false "category id, includes subcategories"
+// @Param cursor query string false "next_cursor of the previous page, replaces page"
+// @Success 200 {object} listAdsResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/favorites [get]
+func (h *ListFavoritesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	guid, ok := currentUser(r)
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+
+	opts, errs := parseListOptions(r)
+	if len(errs) > 0 {
+		problem.Validation(w, errs)
+		return
+	}
+	opts.FavoritedBy = &guid
+	opts.Statuses = []domain.AdStatus{domain.AdPublished}
+
+	writeAdList(w, r, h.posts, h.favorites, opts, guid, true)
+}
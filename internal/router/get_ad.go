@@ -2,11 +2,17 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/imaging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
 )
 
@@ -17,58 +23,100 @@ import (
 // @Produce json
 // @Param id path int true "Ad ID"
 // @Success 200 {object} singleAdResponse
-// @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
 // @Router /ads/{id} [get]
 type GetAdHandler struct {
-	posts *repository.PostRepository
+	posts     *repository.PostRepository
+	favorites *repository.FavoriteRepository
 }
 
-func NewGetAdHandler(posts *repository.PostRepository) *GetAdHandler {
-	return &GetAdHandler{posts: posts}
+func NewGetAdHandler(posts *repository.PostRepository, favorites *repository.FavoriteRepository) *GetAdHandler {
+	return &GetAdHandler{posts: posts, favorites: favorites}
 }
 
 type singleAdResponse struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	ImageURL    string  `json:"image_url"`
-	Price       float64 `json:"price"`
-	AuthorLogin string  `json:"author_login"`
-	IsOwner     bool    `json:"is_owner,omitempty"`
+	ID          int64     `json:"id"`
+	CategoryID  int64     `json:"category_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"image_url"`
+	Price       float64   `json:"price"`
+	AuthorLogin string    `json:"author_login"`
+	IsOwner     bool      `json:"is_owner,omitempty"`
+	IsFavorite  bool      `json:"is_favorite,omitempty"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// RejectionReason is only shown to the owner and moderators
+	RejectionReason string         `json:"rejection_reason,omitempty"`
+	Gallery         []galleryImage `json:"gallery"`
+}
+
+type galleryImage struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	Thumbnail200 string    `json:"thumbnail_200"`
+	Thumbnail640 string    `json:"thumbnail_640"`
 }
 
 func (h *GetAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		problem.BadRequest(w, "ad id must be a positive integer")
 		return
 	}
 
 	post, err := h.posts.Get(r.Context(), id)
-	if err != nil {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
 		return
 	}
 
-	var current uuid.UUID
-	var hasUser bool
-	if idStr, ok := auth.UserIDFromContext(r.Context()); ok {
-		if uid, err := uuid.Parse(idStr); err == nil {
-			current = uid
-			hasUser = true
-		}
+	current, hasUser := currentUser(r)
+	role, _ := auth.RoleFromContext(r.Context())
+	privileged := (hasUser && post.UserGUID == current) || role.AtLeast(domain.RoleModerator)
+	// unpublished and expired ads don't exist for the public
+	public := post.Status == domain.AdPublished && post.ExpiresAt.After(time.Now())
+	if !public && !privileged {
+		problem.NotFound(w)
+		return
 	}
 
 	resp := singleAdResponse{
+		ID:          post.ID,
+		CategoryID:  post.CategoryID,
 		Title:       post.Title,
 		Description: post.Description,
 		ImageURL:    post.ImageURL,
 		Price:       post.Price,
 		AuthorLogin: post.Username,
+		Status:      string(post.Status),
+		ExpiresAt:   post.ExpiresAt,
+		Gallery:     make([]galleryImage, 0, len(post.Images)),
+	}
+	if privileged {
+		resp.RejectionReason = post.RejectionReason
+	}
+	for _, imageID := range post.Images {
+		resp.Gallery = append(resp.Gallery, galleryImage{
+			ID:           imageID,
+			URL:          imageURL(imageID),
+			Thumbnail200: thumbnailURL(imageID, imaging.ThumbnailSmall),
+			Thumbnail640: thumbnailURL(imageID, imaging.ThumbnailMedium),
+		})
 	}
 	if hasUser {
 		resp.IsOwner = post.UserGUID == current
+		favorited, err := h.favorites.Favorited(r.Context(), current, []int64{post.ID})
+		if err != nil {
+			internalError(w, r, "failed to check favorites", err)
+			return
+		}
+		resp.IsFavorite = favorited[post.ID]
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/imaging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+)
+
+type GetImageHandler struct {
+	images  *repository.ImageRepository
+	storage storage.Storage
+}
+
+func NewGetImageHandler(images *repository.ImageRepository, store storage.Storage) *GetImageHandler {
+	return &GetImageHandler{images: images, storage: store}
+}
+
+// ServeHTTP returns the contents of an uploaded image.
+// @Summary Get image
+// @Tags images
+// @Produce image/jpeg,image/png,image/gif
+// @Param id path string true "Image ID"
+// @Param size query int false "thumbnail size, returned as jpeg" Enums(200, 640)
+// @Success 200 {file} file
+// @Failure 404 {object} problem.Problem
+// @Router /images/{id} [get]
+func (h *GetImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		problem.NotFound(w)
+		return
+	}
+
+	size := 0
+	if v := r.URL.Query().Get("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || !slices.Contains(imaging.ThumbnailSizes, size) {
+			problem.Validation(w, []problem.FieldError{
+				{Field: "size", Code: problem.FieldInvalid, Message: "size must be 200 or 640"},
+			})
+			return
+		}
+	}
+
+	image, err := h.images.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get image", err)
+		return
+	}
+
+	var body io.ReadCloser
+	var contentType, length string
+	if size > 0 {
+		body, err = h.storage.Get(r.Context(), thumbnailKey(image.ID, size))
+		contentType, length = "image/jpeg", ""
+	}
+	// images uploaded before thumbnails existed fall back to the original
+	if size == 0 || errors.Is(err, storage.ErrNotFound) {
+		body, err = h.storage.Get(r.Context(), image.ID.String())
+		contentType, length = image.ContentType, strconv.FormatInt(image.Size, 10)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get image", err)
+		return
+	}
+	defer body.Close()
+
+	// images are never modified after upload
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("Content-Type", contentType)
+	if length != "" {
+		w.Header().Set("Content-Length", length)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, body)
+}
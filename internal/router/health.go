@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nerfthisdev/backend-test-task/internal/health"
+)
+
+// readinessTimeout bounds all dependency checks of a single probe.
+const readinessTimeout = 2 * time.Second
+
+type healthResponse struct {
+	Status string                   `json:"status"`
+	Checks map[string]health.Result `json:"checks,omitempty"`
+}
+
+type LivenessHandler struct{}
+
+func NewLivenessHandler() *LivenessHandler {
+	return &LivenessHandler{}
+}
+
+// ServeHTTP reports that the process is up, it never touches dependencies.
+func (h *LivenessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: health.StatusOK})
+}
+
+type ReadinessHandler struct {
+	checker *health.Checker
+}
+
+func NewReadinessHandler(checker *health.Checker) *ReadinessHandler {
+	return &ReadinessHandler{checker: checker}
+}
+
+// ServeHTTP reports whether the service can take traffic, with the result of
+// every dependency check. It answers 503 while any check fails or the server
+// is shutting down.
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks, ready := h.checker.Ready(ctx)
+	if !ready {
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: health.StatusFail, Checks: checks})
+		return
+	}
+	writeHealth(w, http.StatusOK, healthResponse{Status: health.StatusOK, Checks: checks})
+}
+
+func writeHealth(w http.ResponseWriter, status int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
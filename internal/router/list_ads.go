@@ -5,55 +5,108 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
 )
 
+const maxPerPage = 100
+
 type ListAdsHandler struct {
-	posts *repository.PostRepository
+	posts     *repository.PostRepository
+	favorites *repository.FavoriteRepository
 }
 
-func NewListAdsHandler(posts *repository.PostRepository) *ListAdsHandler {
-	return &ListAdsHandler{posts: posts}
+func NewListAdsHandler(posts *repository.PostRepository, favorites *repository.FavoriteRepository) *ListAdsHandler {
+	return &ListAdsHandler{posts: posts, favorites: favorites}
 }
 
 type adResponse struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	ImageURL    string  `json:"image_url"`
-	Price       float64 `json:"price"`
-	AuthorLogin string  `json:"author_login"`
-	IsOwner     bool    `json:"is_owner,omitempty"`
+	ID          int64     `json:"id"`
+	CategoryID  int64     `json:"category_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"image_url"`
+	Price       float64   `json:"price"`
+	AuthorLogin string    `json:"author_login"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	IsOwner     bool      `json:"is_owner,omitempty"`
+	IsFavorite  bool      `json:"is_favorite,omitempty"`
+}
+
+type listAdsResponse struct {
+	Items      []adResponse    `json:"items"`
+	Page       int             `json:"page,omitempty"`
+	PerPage    int             `json:"per_page"`
+	Total      int             `json:"total"`
+	Links      paginationLinks `json:"links"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type paginationLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
 
-// ServeHTTP returns a list of ads with filters.
+// ServeHTTP returns a list of published ads with filters, with mine=true it
+// returns every ad of the current user whatever its status or expiry.
 // @Summary List ads
 // @Tags ads
 // @Accept json
 // @Produce json
 // @Param page query int false "page"
-// @Param per_page query int false "per page"
-// @Param sort_by query string false "sort field" Enums(price, created_at)
+// @Param per_page query int false "per page, at most 100"
+// @Param q query string false "full-text search over title and description"
+// @Param sort_by query string false "sort field, relevance requires q" Enums(price, created_at, relevance)
 // @Param order query string false "order" Enums(asc, desc)
 // @Param min_price query number false "min price"
 // @Param max_price query number false "max price"
-// @Success 200 {array} adResponse
-// @Failure 400 {string} string
+// @Param category query int false "category id, includes subcategories"
+// @Param cursor query string false "next_cursor of the previous page, replaces page"
+// @Param mine query bool false "only ads of the current user, in any status"
+// @Success 200 {object} listAdsResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
 // @Router /ads [get]
 func (h *ListAdsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-		page = 1
+	opts, errs := parseListOptions(r)
+	if len(errs) > 0 {
+		problem.Validation(w, errs)
+		return
+	}
+
+	current, hasUser := currentUser(r)
+	mine := r.URL.Query().Get("mine") == "true"
+	if mine && !hasUser {
+		problem.Unauthorized(w)
+		return
 	}
-	perPage, _ := strconv.Atoi(q.Get("per_page"))
-	if perPage <= 0 {
-		perPage = 10
+	if mine {
+		opts.UserGUID = &current
+		opts.IncludeExpired = true
+	} else {
+		opts.Statuses = []domain.AdStatus{domain.AdPublished}
 	}
+
+	writeAdList(w, r, h.posts, h.favorites, opts, current, hasUser)
+}
+
+// parseListOptions reads the pagination, sorting and filter parameters shared
+// by the ad feeds.
+func parseListOptions(r *http.Request) (repository.ListOptions, []problem.FieldError) {
+	q := r.URL.Query()
+	page, perPage := pageParams(r, 10)
+	search := strings.TrimSpace(q.Get("q"))
 	sortBy := strings.ToLower(q.Get("sort_by"))
-	if sortBy != "price" && sortBy != "created_at" {
+	if sortBy != "price" && sortBy != "created_at" && sortBy != "relevance" {
+		sortBy = "created_at"
+	}
+	if sortBy == "relevance" && search == "" {
 		sortBy = "created_at"
 	}
 	order := strings.ToLower(q.Get("order"))
@@ -64,63 +117,132 @@ func (h *ListAdsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			order = "asc"
 		}
 	}
+	var errs []problem.FieldError
 	var minPricePtr, maxPricePtr *float64
 	if v := q.Get("min_price"); v != "" {
 		f, err := strconv.ParseFloat(v, 64)
 		if err != nil {
-			http.Error(w, "invalid request", http.StatusBadRequest)
-			return
+			errs = append(errs, problem.FieldError{Field: "min_price", Code: problem.FieldInvalid, Message: "min_price must be a number"})
 		}
 		minPricePtr = &f
 	}
 	if v := q.Get("max_price"); v != "" {
 		f, err := strconv.ParseFloat(v, 64)
 		if err != nil {
-			http.Error(w, "invalid request", http.StatusBadRequest)
-			return
+			errs = append(errs, problem.FieldError{Field: "max_price", Code: problem.FieldInvalid, Message: "max_price must be a number"})
 		}
 		maxPricePtr = &f
 	}
+	var after *repository.Cursor
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			errs = append(errs, problem.FieldError{Field: "cursor", Code: problem.FieldInvalid, Message: "cursor is malformed"})
+		} else if (q.Get("sort_by") != "" && sortBy != c.SortBy) || (q.Get("order") != "" && order != c.Order) {
+			errs = append(errs, problem.FieldError{Field: "cursor", Code: problem.FieldInvalid, Message: "cursor was issued for a different sorting"})
+		} else {
+			sortBy, order = c.SortBy, c.Order
+			after = c.position()
+		}
+	}
+	var categoryPtr *int64
+	if v := q.Get("category"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			errs = append(errs, problem.FieldError{Field: "category", Code: problem.FieldInvalid, Message: "category must be a positive integer"})
+		}
+		categoryPtr = &id
+	}
+	return repository.ListOptions{
+		Page:       page,
+		PerPage:    perPage,
+		SortBy:     sortBy,
+		Order:      order,
+		MinPrice:   minPricePtr,
+		MaxPrice:   maxPricePtr,
+		CategoryID: categoryPtr,
+		Query:      search,
+		After:      after,
+	}, errs
+}
 
-	opts := repository.ListOptions{
-		Page:     page,
-		PerPage:  perPage,
-		SortBy:   sortBy,
-		Order:    order,
-		MinPrice: minPricePtr,
-		MaxPrice: maxPricePtr,
+// currentUser returns the authenticated user of the request, if any.
+func currentUser(r *http.Request) (uuid.UUID, bool) {
+	idStr, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, false
 	}
+	uid, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return uid, true
+}
 
-	posts, err := h.posts.List(r.Context(), opts)
+// writeAdList writes a page of the ads matching opts in the listAdsResponse
+// envelope.
+func writeAdList(w http.ResponseWriter, r *http.Request, repo *repository.PostRepository, favorites *repository.FavoriteRepository, opts repository.ListOptions, current uuid.UUID, hasUser bool) {
+	page, perPage, sortBy, order, after := opts.Page, opts.PerPage, opts.SortBy, opts.Order, opts.After
+
+	posts, hasMore, err := repo.List(r.Context(), opts)
 	if err != nil {
-		http.Error(w, "failed to list ads", http.StatusInternalServerError)
+		internalError(w, r, "failed to list ads", err)
+		return
+	}
+	total, err := repo.Count(r.Context(), opts)
+	if err != nil {
+		internalError(w, r, "failed to count ads", err)
 		return
 	}
 
-	var current uuid.UUID
-	var hasUser bool
-	if idStr, ok := auth.UserIDFromContext(r.Context()); ok {
-		if uid, err := uuid.Parse(idStr); err == nil {
-			current = uid
-			hasUser = true
+	resp := listAdsResponse{
+		Items:   make([]adResponse, 0, len(posts)),
+		PerPage: perPage,
+		Total:   total,
+	}
+	if hasMore && sortBy != "relevance" {
+		resp.NextCursor = encodeCursor(sortBy, order, posts[len(posts)-1])
+	}
+	if after != nil {
+		// pages are not numbered when scrolling by cursor
+		if resp.NextCursor != "" {
+			resp.Links.Next = pageLink(r, "cursor", resp.NextCursor)
+		}
+	} else {
+		resp.Page = page
+		resp.Links = pageLinks(r, page, hasMore)
+	}
+	var favorited map[int64]bool
+	if hasUser {
+		ids := make([]int64, len(posts))
+		for i, p := range posts {
+			ids[i] = p.ID
+		}
+		if favorited, err = favorites.Favorited(r.Context(), current, ids); err != nil {
+			internalError(w, r, "failed to check favorites", err)
+			return
 		}
 	}
-
-	resp := make([]adResponse, 0, len(posts))
 	for _, p := range posts {
 		item := adResponse{
+			ID:          p.ID,
+			CategoryID:  p.CategoryID,
 			Title:       p.Title,
 			Description: p.Description,
 			ImageURL:    p.ImageURL,
 			Price:       p.Price,
 			AuthorLogin: p.Username,
+			Status:      string(p.Status),
+			ExpiresAt:   p.ExpiresAt,
 		}
 		if hasUser {
 			item.IsOwner = p.UserGUID == current
+			item.IsFavorite = favorited[p.ID]
 		}
-		resp = append(resp, item)
+		resp.Items = append(resp.Items, item)
 	}
 
+	setLinkHeader(w, resp.Links)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
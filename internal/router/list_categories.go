@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+type ListCategoriesHandler struct {
+	categories *repository.CategoryRepository
+}
+
+func NewListCategoriesHandler(categories *repository.CategoryRepository) *ListCategoriesHandler {
+	return &ListCategoriesHandler{categories: categories}
+}
+
+type categoryResponse struct {
+	ID       int64              `json:"id"`
+	Slug     string             `json:"slug"`
+	Name     string             `json:"name"`
+	Children []categoryResponse `json:"children"`
+}
+
+// ServeHTTP returns the category tree.
+// @Summary List categories
+// @Tags categories
+// @Produce json
+// @Success 200 {array} categoryResponse
+// @Router /categories [get]
+func (h *ListCategoriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categories.List(r.Context())
+	if err != nil {
+		internalError(w, r, "failed to list categories", err)
+		return
+	}
+
+	children := make(map[int64][]int64)
+	var roots []int64
+	byID := make(map[int64]categoryResponse, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = categoryResponse{ID: c.ID, Slug: c.Slug, Name: c.Name}
+		if c.ParentID == nil {
+			roots = append(roots, c.ID)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+
+	var build func(id int64) categoryResponse
+	build = func(id int64) categoryResponse {
+		node := byID[id]
+		node.Children = make([]categoryResponse, 0, len(children[id]))
+		for _, child := range children[id] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+
+	resp := make([]categoryResponse, 0, len(roots))
+	for _, id := range roots {
+		resp = append(resp, build(id))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
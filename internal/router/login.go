@@ -2,66 +2,119 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/metrics"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
 )
 
 type LoginHandler struct {
-	users  *repository.UserRepository
-	tokens *auth.JWTService
+	users    *repository.UserRepository
+	sessions *auth.SessionManager
+	lockout  repository.LockoutPolicy
 }
 
-func NewLoginHandler(users *repository.UserRepository, tokens *auth.JWTService) *LoginHandler {
-	return &LoginHandler{users: users, tokens: tokens}
+func NewLoginHandler(users *repository.UserRepository, sessions *auth.SessionManager, lockout repository.LockoutPolicy) *LoginHandler {
+	return &LoginHandler{users: users, sessions: sessions, lockout: lockout}
 }
 
 type loginRequest struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login    string `json:"login" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
-type loginResponse struct {
-	Token string `json:"token"`
-}
-
-// ServeHTTP authenticates the user and returns a token.
+// ServeHTTP authenticates the user and starts a new session.
 // @Summary Login user
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param data body loginRequest true "credentials"
-// @Success 200 {object} loginResponse
-// @Failure 401 {string} string
+// @Success 200 {object} domain.TokenPair
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 423 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
 // @Router /login [post]
 func (h *LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
 	user, err := h.users.GetByUsername(r.Context(), req.Login)
-	if err != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		internalError(w, r, "failed to get user", err)
+		return
+	} else if err != nil {
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginFailure).Inc()
+		problem.Write(w, http.StatusUnauthorized, problem.CodeInvalidCredentials, "invalid login or password")
+		return
+	}
+
+	// locked accounts are refused before bcrypt, guessing can't continue
+	// and doesn't cost CPU
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginLocked).Inc()
+		accountLocked(w, *user.LockedUntil)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginFailure).Inc()
+		attempt := domain.LoginAttempt{UserGUID: user.GUID, IP: clientIP(r), UserAgent: r.UserAgent()}
+		lockedUntil, err := h.users.RecordFailedLogin(r.Context(), attempt, h.lockout)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to record failed login", zap.Error(err))
+		} else if lockedUntil != nil {
+			logging.FromContext(r.Context()).Warn("account locked", zap.String("user_id", user.GUID.String()), zap.Time("until", *lockedUntil))
+			accountLocked(w, *lockedUntil)
+			return
+		}
+		problem.Write(w, http.StatusUnauthorized, problem.CodeInvalidCredentials, "invalid login or password")
+		return
+	}
+	if err := h.users.ResetFailedLogins(r.Context(), user.GUID); err != nil {
+		internalError(w, r, "failed to reset failed logins", err)
+		return
+	}
+	// only reported once the password is proven, so the ban reason is not
+	// shown to anyone guessing
+	if user.BannedAt != nil {
+		detail := "account is banned"
+		if user.BanReason != "" {
+			detail += ": " + user.BanReason
+		}
+		problem.Write(w, http.StatusForbidden, problem.CodeUserBanned, detail)
 		return
 	}
 
-	token, err := h.tokens.GenerateAccessToken(user.GUID)
+	pair, err := h.sessions.Start(r.Context(), *user, clientIP(r), r.UserAgent())
 	if err != nil {
-		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		internalError(w, r, "failed to generate token", err)
 		return
 	}
+	metrics.LoginAttempts.WithLabelValues(metrics.LoginSuccess).Inc()
 
-	w.Header().Set("X-Auth-Token", token)
+	w.Header().Set("X-Auth-Token", pair.AccessToken)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(loginResponse{Token: token})
+	json.NewEncoder(w).Encode(pair)
+}
+
+func accountLocked(w http.ResponseWriter, until time.Time) {
+	seconds := int(math.Ceil(time.Until(until).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	problem.Write(w, http.StatusLocked, problem.CodeAccountLocked,
+		"account is locked after too many failed logins until "+until.UTC().Format(time.RFC3339))
 }
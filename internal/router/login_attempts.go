@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+// maxLoginAttempts is how many recent failed logins are shown.
+const maxLoginAttempts = 50
+
+type ListLoginAttemptsHandler struct {
+	users *repository.UserRepository
+}
+
+func NewListLoginAttemptsHandler(users *repository.UserRepository) *ListLoginAttemptsHandler {
+	return &ListLoginAttemptsHandler{users: users}
+}
+
+type loginAttemptResponse struct {
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ServeHTTP returns the recent failed logins of the current user, newest first.
+// @Summary List failed logins
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} loginAttemptResponse
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/login-attempts [get]
+func (h *ListLoginAttemptsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	attempts, err := h.users.ListFailedLogins(r.Context(), guid, maxLoginAttempts)
+	if err != nil {
+		internalError(w, r, "failed to list login attempts", err)
+		return
+	}
+
+	resp := make([]loginAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		resp = append(resp, loginAttemptResponse{IP: a.IP, UserAgent: a.UserAgent, CreatedAt: a.CreatedAt})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+type LogoutHandler struct {
+	sessions *auth.SessionManager
+}
+
+func NewLogoutHandler(sessions *auth.SessionManager) *LogoutHandler {
+	return &LogoutHandler{sessions: sessions}
+}
+
+// ServeHTTP revokes the session the token belongs to.
+// @Summary Logout
+// @Tags auth
+// @Success 204
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /logout [post]
+func (h *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := auth.SessionIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+
+	if err := h.sessions.Revoke(r.Context(), sessionID); err != nil {
+		internalError(w, r, "failed to logout", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type LogoutAllHandler struct {
+	sessions *auth.SessionManager
+}
+
+func NewLogoutAllHandler(sessions *auth.SessionManager) *LogoutAllHandler {
+	return &LogoutAllHandler{sessions: sessions}
+}
+
+// ServeHTTP revokes every session of the current user.
+// @Summary Logout from all devices
+// @Tags auth
+// @Success 204
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /logout-all [post]
+func (h *LogoutAllHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	if err := h.sessions.RevokeAll(r.Context(), guid); err != nil {
+		internalError(w, r, "failed to logout", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+type ModerationQueueHandler struct {
+	posts *repository.PostRepository
+}
+
+func NewModerationQueueHandler(posts *repository.PostRepository) *ModerationQueueHandler {
+	return &ModerationQueueHandler{posts: posts}
+}
+
+// ServeHTTP lists ads waiting for review, the one waiting longest first.
+// @Summary Moderation queue
+// @Tags moderation
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page, at most 100"
+// @Success 200 {object} listAdsResponse
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Security XAuthToken
+// @Router /moderation/ads [get]
+func (h *ModerationQueueHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, perPage := pageParams(r, 10)
+
+	opts := repository.ListOptions{
+		Page:     page,
+		PerPage:  perPage,
+		SortBy:   "status_changed_at",
+		Order:    "asc",
+		Statuses: []domain.AdStatus{domain.AdPendingReview},
+		// an ad gets a fresh lifetime when approved
+		IncludeExpired: true,
+	}
+	posts, hasMore, err := h.posts.List(r.Context(), opts)
+	if err != nil {
+		internalError(w, r, "failed to list moderation queue", err)
+		return
+	}
+	total, err := h.posts.Count(r.Context(), opts)
+	if err != nil {
+		internalError(w, r, "failed to count moderation queue", err)
+		return
+	}
+
+	resp := listAdsResponse{
+		Items:   make([]adResponse, 0, len(posts)),
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	}
+	resp.Links = pageLinks(r, page, hasMore)
+	for _, p := range posts {
+		resp.Items = append(resp.Items, adResponse{
+			ID:          p.ID,
+			CategoryID:  p.CategoryID,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageURL,
+			Price:       p.Price,
+			AuthorLogin: p.Username,
+			Status:      string(p.Status),
+			ExpiresAt:   p.ExpiresAt,
+		})
+	}
+
+	setLinkHeader(w, resp.Links)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type ApproveAdHandler struct {
+	posts *repository.PostRepository
+}
+
+func NewApproveAdHandler(posts *repository.PostRepository) *ApproveAdHandler {
+	return &ApproveAdHandler{posts: posts}
+}
+
+// ServeHTTP publishes an ad waiting for review.
+// @Summary Approve ad
+// @Tags moderation
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Success 200 {object} adStatusResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security XAuthToken
+// @Router /moderation/ads/{id}/approve [post]
+func (h *ApproveAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	if !transition(w, r, h.posts, id, domain.AdPublished, "") {
+		return
+	}
+	logging.FromContext(r.Context()).Info("ad approved", zap.Int64("ad_id", id))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adStatusResponse{ID: id, Status: string(domain.AdPublished)})
+}
+
+type RejectAdHandler struct {
+	posts *repository.PostRepository
+}
+
+func NewRejectAdHandler(posts *repository.PostRepository) *RejectAdHandler {
+	return &RejectAdHandler{posts: posts}
+}
+
+type rejectAdRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ServeHTTP rejects an ad waiting for review, the reason is shown to its author.
+// @Summary Reject ad
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param input body rejectAdRequest true "rejection reason"
+// @Success 200 {object} adStatusResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security XAuthToken
+// @Router /moderation/ads/{id}/reject [post]
+func (h *RejectAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	var req rejectAdRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if !transition(w, r, h.posts, id, domain.AdRejected, req.Reason) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("ad rejected", zap.Int64("ad_id", id), zap.String("reason", req.Reason))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adStatusResponse{ID: id, Status: string(domain.AdRejected), RejectionReason: req.Reason})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// pageParams reads page and per_page from the query, capping per_page at
+// maxPerPage.
+func pageParams(r *http.Request, defaultPerPage int) (int, int) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return page, perPage
+}
+
+// pageLink returns the request URL with the page or cursor parameter
+// replaced. The two are mutually exclusive so the other one is dropped.
+func pageLink(r *http.Request, key, value string) string {
+	q := r.URL.Query()
+	q.Del("page")
+	q.Del("cursor")
+	q.Set(key, value)
+
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// pageLinks returns the next and prev links of a page-numbered list.
+func pageLinks(r *http.Request, page int, hasNext bool) paginationLinks {
+	var links paginationLinks
+	if hasNext {
+		links.Next = pageLink(r, "page", strconv.Itoa(page+1))
+	}
+	if page > 1 {
+		links.Prev = pageLink(r, "page", strconv.Itoa(page-1))
+	}
+	return links
+}
+
+// setLinkHeader writes the pagination links as an RFC 8288 Link header.
+func setLinkHeader(w http.ResponseWriter, links paginationLinks) {
+	var parts []string
+	if links.Next != "" {
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="next"`, links.Next))
+	}
+	if links.Prev != "" {
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="prev"`, links.Prev))
+	}
+	if len(parts) > 0 {
+		w.Header().Set("Link", strings.Join(parts, ", "))
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+func ipKey(r *http.Request) string {
+	return clientIP(r)
+}
+
+// loginKey buckets login attempts by the username in the body, so spreading
+// a password guessing run over many IPs doesn't help. The body is restored
+// for the handler.
+func loginKey(r *http.Request) string {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Login string `json:"login"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Login == "" {
+		return ""
+	}
+	// usernames are stored as typed, bucket case variants together anyway
+	return strings.ToLower(req.Login)
+}
+
+// realIP replaces RemoteAddr with the client address reported by the reverse
+// proxy in front of us. traefik appends the peer it saw, so the last
+// X-Forwarded-For entry is the only one that can't be forged by clients.
+func realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
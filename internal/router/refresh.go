@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+type RefreshHandler struct {
+	sessions *auth.SessionManager
+}
+
+func NewRefreshHandler(sessions *auth.SessionManager) *RefreshHandler {
+	return &RefreshHandler{sessions: sessions}
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// ServeHTTP exchanges a refresh token for a new token pair.
+// @Summary Refresh tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body refreshRequest true "refresh token"
+// @Success 200 {object} domain.TokenPair
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Router /refresh [post]
+func (h *RefreshHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	pair, err := h.sessions.Refresh(r.Context(), req.RefreshToken, clientIP(r), r.UserAgent())
+	if errors.Is(err, auth.ErrInvalidRefreshToken) {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeInvalidRefreshToken, "invalid refresh token")
+		return
+	} else if errors.Is(err, auth.ErrRefreshTokenExpired) {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeRefreshTokenExpired, "refresh token expired")
+		return
+	} else if errors.Is(err, auth.ErrUserBanned) {
+		problem.Write(w, http.StatusForbidden, problem.CodeUserBanned, "account is banned")
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to refresh token", err)
+		return
+	}
+
+	w.Header().Set("X-Auth-Token", pair.AccessToken)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pair)
+}
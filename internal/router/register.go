@@ -4,13 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"regexp"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
 )
 
@@ -23,8 +23,8 @@ func NewRegisterHandler(users *repository.UserRepository) *RegisterHandler {
 }
 
 type registerRequest struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login    string `json:"login" validate:"required,min=3,max=20,alnum"`
+	Password string `json:"password" validate:"required,min=8,max=32,letter,digit"`
 }
 
 type registerResponse struct {
@@ -32,23 +32,6 @@ type registerResponse struct {
 	Username string    `json:"username"`
 }
 
-func isValidLogin(login string) bool {
-	if len(login) < 3 || len(login) > 20 {
-		return false
-	}
-	matched, _ := regexp.MatchString(`^[a-zA-Z0-9]+$`, login)
-	return matched
-}
-
-func isValidPassword(password string) bool {
-	if len(password) < 8 || len(password) > 32 {
-		return false
-	}
-	hasLetter, _ := regexp.MatchString(`[A-Za-z]`, password)
-	hasDigit, _ := regexp.MatchString(`[0-9]`, password)
-	return hasLetter && hasDigit
-}
-
 // ServeHTTP registers a new user.
 // @Summary Register new user
 // @Tags auth
@@ -56,31 +39,27 @@ func isValidPassword(password string) bool {
 // @Produce json
 // @Param data body registerRequest true "credentials"
 // @Success 200 {object} registerResponse
-// @Failure 400 {string} string
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
 // @Router /register [post]
 func (h *RegisterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
-		return
-	}
-
-	if !isValidLogin(req.Login) || !isValidPassword(req.Password) {
-		http.Error(w, "invalid credentials", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
 	if _, err := h.users.GetByUsername(r.Context(), req.Login); err == nil {
-		http.Error(w, "user already exists", http.StatusConflict)
+		problem.Write(w, http.StatusConflict, problem.CodeUserExists, "user already exists")
 		return
 	} else if !errors.Is(err, pgx.ErrNoRows) {
-		http.Error(w, "failed to check user", http.StatusInternalServerError)
+		internalError(w, r, "failed to check user", err)
 		return
 	}
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "failed to hash password", http.StatusInternalServerError)
+		internalError(w, r, "failed to hash password", err)
 		return
 	}
 
@@ -91,7 +70,7 @@ func (h *RegisterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.users.Create(r.Context(), user); err != nil {
-		http.Error(w, "failed to create user", http.StatusInternalServerError)
+		internalError(w, r, "failed to create user", err)
 		return
 	}
 
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+)
+
+type RenewAdHandler struct {
+	posts *repository.PostRepository
+}
+
+func NewRenewAdHandler(posts *repository.PostRepository) *RenewAdHandler {
+	return &RenewAdHandler{posts: posts}
+}
+
+type renewAdResponse struct {
+	ID        int64     `json:"id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ServeHTTP extends the lifetime of an ad owned by the current user, an
+// archived ad is sent back to review.
+// @Summary Renew ad
+// @Tags ads
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Success 200 {object} renewAdResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id}/renew [post]
+func (h *RenewAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+	if ad.UserGUID != guid {
+		problem.Forbidden(w)
+		return
+	}
+
+	status, expiresAt, err := h.posts.Renew(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if errors.Is(err, repository.ErrInvalidTransition) {
+		problem.Write(w, http.StatusConflict, problem.CodeInvalidTransition, "only published, pending or archived ads can be renewed")
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to renew ad", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(renewAdResponse{ID: id, Status: string(status), ExpiresAt: expiresAt})
+}
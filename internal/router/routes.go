@@ -5,25 +5,113 @@ import (
 
 	_ "github.com/nerfthisdev/backend-test-task/docs"
 	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/config"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/health"
 	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/metrics"
+	"github.com/nerfthisdev/backend-test-task/internal/ratelimit"
 	"github.com/nerfthisdev/backend-test-task/internal/repository"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+	"github.com/nerfthisdev/backend-test-task/internal/tracing"
+	"github.com/nerfthisdev/backend-test-task/internal/validation"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
 )
 
-func NewRouter(users *repository.UserRepository, posts *repository.PostRepository, tokens *auth.JWTService, logger *zap.Logger) http.Handler {
+// Deps holds everything the handlers need.
+type Deps struct {
+	Users         *repository.UserRepository
+	Posts         *repository.PostRepository
+	Categories    *repository.CategoryRepository
+	Images        *repository.ImageRepository
+	Favorites     *repository.FavoriteRepository
+	Conversations *repository.ConversationRepository
+	TokenRepo     *repository.TokenRepository
+	Tokens        *auth.JWTService
+	Sessions      *auth.SessionManager
+	Storage       storage.Storage
+	Health        *health.Checker
+	Limiter       ratelimit.Limiter
+	RateLimits    config.RateLimits
+	TrustProxy    bool
+	Lockout       repository.LockoutPolicy
+	// MaxUploadSize is the largest accepted image upload in bytes
+	MaxUploadSize int64
+	Logger        *zap.Logger
+}
+
+func NewRouter(d Deps) http.Handler {
+	validation.MustRegister(requestDTOs...)
+
 	mux := http.NewServeMux()
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
-	mux.Handle("POST /api/v1/register", NewRegisterHandler(users))
-	mux.Handle("POST /api/v1/login", NewLoginHandler(users, tokens))
+	mux.Handle("GET /healthz", NewLivenessHandler())
+	mux.Handle("GET /readyz", NewReadinessHandler(d.Health))
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	registerIPLimit := ratelimit.Middleware(d.Limiter, "register_ip", d.RateLimits.RegisterIP, ipKey)
+	loginIPLimit := ratelimit.Middleware(d.Limiter, "login_ip", d.RateLimits.LoginIP, ipKey)
+	loginUserLimit := ratelimit.Middleware(d.Limiter, "login_user", d.RateLimits.LoginUser, loginKey)
+
+	mux.Handle("POST /api/v1/register", registerIPLimit(NewRegisterHandler(d.Users)))
+	mux.Handle("POST /api/v1/login", loginIPLimit(loginUserLimit(NewLoginHandler(d.Users, d.Sessions, d.Lockout))))
+	mux.Handle("POST /api/v1/refresh", NewRefreshHandler(d.Sessions))
+
+	authenticated := auth.AuthMiddleware(d.Tokens, d.TokenRepo)
+	optionalAuth := auth.OptionalAuthMiddleware(d.Tokens, d.TokenRepo)
+
+	mux.Handle("POST /api/v1/logout", authenticated(NewLogoutHandler(d.Sessions)))
+	mux.Handle("POST /api/v1/logout-all", authenticated(NewLogoutAllHandler(d.Sessions)))
+	mux.Handle("GET /api/v1/me/sessions", authenticated(NewListSessionsHandler(d.Sessions)))
+	mux.Handle("DELETE /api/v1/me/sessions/{id}", authenticated(NewDeleteSessionHandler(d.Sessions)))
+	mux.Handle("GET /api/v1/me/login-attempts", authenticated(NewListLoginAttemptsHandler(d.Users)))
+
+	mux.Handle("GET /api/v1/categories", NewListCategoriesHandler(d.Categories))
+
+	mux.Handle("POST /api/v1/images", authenticated(NewUploadImageHandler(d.Images, d.Storage, d.MaxUploadSize)))
+	mux.Handle("GET /api/v1/images/{id}", NewGetImageHandler(d.Images, d.Storage))
+
+	createAd := NewCreateAdHandler(d.Posts, d.Categories, d.Images)
+	listAds := NewListAdsHandler(d.Posts, d.Favorites)
+	getAd := NewGetAdHandler(d.Posts, d.Favorites)
+	updateAd := NewUpdateAdHandler(d.Posts, d.Categories, d.Images, d.Storage)
+	deleteAd := NewDeleteAdHandler(d.Posts, d.Storage)
+
+	mux.Handle("POST /api/v1/ads", authenticated(createAd))
+	mux.Handle("GET /api/v1/ads", optionalAuth(listAds))
+	mux.Handle("GET /api/v1/ads/{id}", optionalAuth(getAd))
+	mux.Handle("PATCH /api/v1/ads/{id}", authenticated(updateAd))
+	mux.Handle("DELETE /api/v1/ads/{id}", authenticated(deleteAd))
+	mux.Handle("PUT /api/v1/ads/{id}/status", authenticated(NewSetAdStatusHandler(d.Posts)))
+	mux.Handle("POST /api/v1/ads/{id}/renew", authenticated(NewRenewAdHandler(d.Posts)))
+	mux.Handle("PUT /api/v1/ads/{id}/favorite", authenticated(NewAddFavoriteHandler(d.Posts, d.Favorites)))
+	mux.Handle("DELETE /api/v1/ads/{id}/favorite", authenticated(NewRemoveFavoriteHandler(d.Favorites)))
+	mux.Handle("GET /api/v1/me/favorites", authenticated(NewListFavoritesHandler(d.Posts, d.Favorites)))
+
+	mux.Handle("POST /api/v1/ads/{id}/messages", authenticated(NewSendAdMessageHandler(d.Posts, d.Conversations)))
+	mux.Handle("GET /api/v1/me/conversations", authenticated(NewListConversationsHandler(d.Conversations)))
+	mux.Handle("GET /api/v1/me/conversations/{id}/messages", authenticated(NewListMessagesHandler(d.Conversations)))
+	mux.Handle("POST /api/v1/me/conversations/{id}/messages", authenticated(NewReplyHandler(d.Conversations)))
+	mux.Handle("POST /api/v1/me/conversations/{id}/read", authenticated(NewMarkConversationReadHandler(d.Conversations)))
+
+	admin := func(h http.Handler) http.Handler { return authenticated(auth.RequireRole(domain.RoleAdmin)(h)) }
+	moderator := func(h http.Handler) http.Handler { return authenticated(auth.RequireRole(domain.RoleModerator)(h)) }
 
-	createAd := NewCreateAdHandler(posts)
-	listAds := NewListAdsHandler(posts)
-	getAd := NewGetAdHandler(posts)
+	mux.Handle("GET /api/v1/admin/users", admin(NewListUsersHandler(d.Users)))
+	mux.Handle("POST /api/v1/admin/users/{guid}/ban", admin(NewBanUserHandler(d.Users, d.Sessions)))
+	mux.Handle("DELETE /api/v1/admin/users/{guid}/ban", admin(NewUnbanUserHandler(d.Users)))
+	mux.Handle("PUT /api/v1/admin/users/{guid}/role", admin(NewSetUserRoleHandler(d.Users, d.Sessions)))
+	mux.Handle("DELETE /api/v1/admin/ads/{id}", moderator(NewRemoveAdHandler(d.Posts, d.Storage)))
 
-	mux.Handle("POST /api/v1/ads", auth.AuthMiddleware(tokens)(createAd))
-	mux.Handle("GET /api/v1/ads", listAds)
-	mux.Handle("GET /api/v1/ads/{id}", getAd)
+	mux.Handle("GET /api/v1/moderation/ads", moderator(NewModerationQueueHandler(d.Posts)))
+	mux.Handle("POST /api/v1/moderation/ads/{id}/approve", moderator(NewApproveAdHandler(d.Posts)))
+	mux.Handle("POST /api/v1/moderation/ads/{id}/reject", moderator(NewRejectAdHandler(d.Posts)))
 
-	return logging.LoggingMiddleware(logger)(mux)
+	handler := logging.LoggingMiddleware(d.Logger)(tracing.Middleware(metrics.Middleware(mux)))
+	if d.TrustProxy {
+		handler = realIP(handler)
+	}
+	return handler
 }
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+type ListSessionsHandler struct {
+	sessions *auth.SessionManager
+}
+
+func NewListSessionsHandler(sessions *auth.SessionManager) *ListSessionsHandler {
+	return &ListSessionsHandler{sessions: sessions}
+}
+
+type sessionResponse struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+// ServeHTTP returns the active sessions of the current user.
+// @Summary List sessions
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} sessionResponse
+// @Failure 401 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/sessions [get]
+func (h *ListSessionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	sessions, err := h.sessions.List(r.Context(), guid)
+	if err != nil {
+		internalError(w, r, "failed to list sessions", err)
+		return
+	}
+
+	current, _ := auth.SessionIDFromContext(r.Context())
+
+	resp := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, sessionResponse{
+			ID:        s.SessionID,
+			IP:        s.IP,
+			UserAgent: s.UserAgent,
+			CreatedAt: s.CreatedAt,
+			ExpiresAt: s.ExpiresAt,
+			Current:   s.SessionID == current,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type DeleteSessionHandler struct {
+	sessions *auth.SessionManager
+}
+
+func NewDeleteSessionHandler(sessions *auth.SessionManager) *DeleteSessionHandler {
+	return &DeleteSessionHandler{sessions: sessions}
+}
+
+// ServeHTTP terminates one of the current user's sessions.
+// @Summary Terminate session
+// @Tags sessions
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /me/sessions/{id} [delete]
+func (h *DeleteSessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	err = h.sessions.RevokeOwned(r.Context(), guid, r.PathValue("id"))
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to terminate session", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/imaging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+)
+
+type UpdateAdHandler struct {
+	posts      *repository.PostRepository
+	categories *repository.CategoryRepository
+	images     *repository.ImageRepository
+	storage    storage.Storage
+}
+
+func NewUpdateAdHandler(posts *repository.PostRepository, categories *repository.CategoryRepository, images *repository.ImageRepository, store storage.Storage) *UpdateAdHandler {
+	return &UpdateAdHandler{posts: posts, categories: categories, images: images, storage: store}
+}
+
+type updateAdRequest struct {
+	CategoryID  *int64      `json:"category_id" validate:"min=1"`
+	Title       *string     `json:"title" validate:"min=1,max=100"`
+	Description *string     `json:"description" validate:"min=1,max=500"`
+	Images      []uuid.UUID `json:"images" validate:"min=1,max=10,unique"`
+	Price       *float64    `json:"price" validate:"min=0"`
+}
+
+// ServeHTTP partially updates an ad owned by the current user.
+// @Summary Update ad
+// @Tags ads
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param data body updateAdRequest true "fields to change"
+// @Success 200 {object} domain.Post
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security XAuthToken
+// @Router /ads/{id} [patch]
+func (h *UpdateAdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		problem.BadRequest(w, "ad id must be a positive integer")
+		return
+	}
+
+	var req updateAdRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	ad, err := h.posts.Get(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		problem.NotFound(w)
+		return
+	} else if err != nil {
+		internalError(w, r, "failed to get ad", err)
+		return
+	}
+	if ad.UserGUID != guid {
+		problem.Forbidden(w)
+		return
+	}
+
+	post := domain.Post{
+		ID:          ad.ID,
+		UserGUID:    ad.UserGUID,
+		CategoryID:  ad.CategoryID,
+		Title:       ad.Title,
+		Description: ad.Description,
+		ImageURL:    ad.ImageURL,
+		Price:       ad.Price,
+	}
+	if req.CategoryID != nil {
+		post.CategoryID = *req.CategoryID
+	}
+	if req.Title != nil {
+		post.Title = *req.Title
+	}
+	if req.Description != nil {
+		post.Description = *req.Description
+	}
+	// a missing images field keeps the gallery as is
+	if len(req.Images) > 0 {
+		post.Images = req.Images
+		post.ImageURL = thumbnailURL(req.Images[0], imaging.ThumbnailMedium)
+	}
+	if req.Price != nil {
+		post.Price = *req.Price
+	}
+
+	if req.CategoryID != nil {
+		if exists, err := h.categories.Exists(r.Context(), post.CategoryID); err != nil {
+			internalError(w, r, "failed to check category", err)
+			return
+		} else if !exists {
+			problem.Validation(w, []problem.FieldError{
+				{Field: "category_id", Code: problem.FieldNotFound, Message: "category does not exist"},
+			})
+			return
+		}
+	}
+
+	if req.Images != nil {
+		if owned, err := areOwnedImages(r.Context(), h.images, req.Images, guid); err != nil {
+			internalError(w, r, "failed to check image", err)
+			return
+		} else if !owned {
+			problem.Validation(w, []problem.FieldError{
+				{Field: "images", Code: problem.FieldNotFound, Message: "images must be uploaded by you"},
+			})
+			return
+		}
+	}
+
+	updated, removed, err := h.posts.Update(r.Context(), post)
+	if err != nil {
+		internalError(w, r, "failed to update ad", err)
+		return
+	}
+	deleteImages(r.Context(), h.storage, removed)
+	if updated.Images == nil {
+		updated.Images = ad.Images
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/auth"
+	"github.com/nerfthisdev/backend-test-task/internal/domain"
+	"github.com/nerfthisdev/backend-test-task/internal/imaging"
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+	"github.com/nerfthisdev/backend-test-task/internal/repository"
+	"github.com/nerfthisdev/backend-test-task/internal/storage"
+)
+
+// multipartOverhead leaves room for the multipart boundaries and headers on
+// top of the file itself.
+const multipartOverhead = 1 << 20
+
+// allowedImageTypes are the formats the standard library can decode, which
+// is required to generate thumbnails.
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type UploadImageHandler struct {
+	images  *repository.ImageRepository
+	storage storage.Storage
+	maxSize int64
+	// decodeSlots bounds how many uploads are decoded and resized at once,
+	// a decoded image takes far more memory than the upload itself
+	decodeSlots chan struct{}
+}
+
+func NewUploadImageHandler(images *repository.ImageRepository, store storage.Storage, maxSize int64) *UploadImageHandler {
+	return &UploadImageHandler{
+		images:      images,
+		storage:     store,
+		maxSize:     maxSize,
+		decodeSlots: make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+type imageResponse struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	Thumbnail200 string    `json:"thumbnail_200"`
+	Thumbnail640 string    `json:"thumbnail_640"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+}
+
+func newImageResponse(image domain.Image) imageResponse {
+	return imageResponse{
+		ID:           image.ID,
+		URL:          imageURL(image.ID),
+		Thumbnail200: thumbnailURL(image.ID, imaging.ThumbnailSmall),
+		Thumbnail640: thumbnailURL(image.ID, imaging.ThumbnailMedium),
+		ContentType:  image.ContentType,
+		Size:         image.Size,
+	}
+}
+
+func imageURL(id uuid.UUID) string {
+	return "/api/v1/images/" + id.String()
+}
+
+func thumbnailURL(id uuid.UUID, size int) string {
+	return fmt.Sprintf("%s?size=%d", imageURL(id), size)
+}
+
+func thumbnailKey(id uuid.UUID, size int) string {
+	return fmt.Sprintf("%s_%d", id, size)
+}
+
+// areOwnedImages reports whether all images exist and were uploaded by owner.
+func areOwnedImages(ctx context.Context, images *repository.ImageRepository, ids []uuid.UUID, owner uuid.UUID) (bool, error) {
+	for _, id := range ids {
+		image, err := images.Get(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if image.UserGUID != owner {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ServeHTTP stores an uploaded image.
+// @Summary Upload image
+// @Tags images
+// @Accept mpfd
+// @Produce json
+// @Param image formData file true "jpeg, png or gif image"
+// @Success 200 {object} imageResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Security XAuthToken
+// @Router /images [post]
+func (h *UploadImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		problem.Unauthorized(w)
+		return
+	}
+	guid, err := uuid.Parse(userID)
+	if err != nil {
+		problem.Unauthorized(w)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+multipartOverhead)
+	file, header, err := r.FormFile("image")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "image too large")
+		return
+	} else if err != nil {
+		problem.Validation(w, []problem.FieldError{
+			{Field: "image", Code: problem.FieldRequired, Message: "image file is required"},
+		})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.maxSize {
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "image too large")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, h.maxSize+1))
+	if err != nil {
+		problem.BadRequest(w, "failed to read image")
+		return
+	}
+	if int64(len(data)) > h.maxSize {
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "image too large")
+		return
+	}
+
+	// the declared content type is ignored, only the file signature counts
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		problem.Write(w, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "only jpeg, png and gif images are accepted")
+		return
+	}
+
+	select {
+	case h.decodeSlots <- struct{}{}:
+		defer func() { <-h.decodeSlots }()
+	case <-r.Context().Done():
+		// the client is gone, nobody is waiting for the answer
+		return
+	}
+
+	decoded, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		problem.Write(w, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "image too large")
+		return
+	} else if err != nil {
+		problem.Validation(w, []problem.FieldError{
+			{Field: "image", Code: problem.FieldInvalid, Message: "image could not be decoded"},
+		})
+		return
+	}
+
+	image := domain.Image{
+		ID:          uuid.New(),
+		UserGUID:    guid,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	keys := []string{image.ID.String()}
+	if err := h.storage.Put(r.Context(), keys[0], bytes.NewReader(data)); err != nil {
+		internalError(w, r, "failed to store image", err)
+		return
+	}
+
+	for _, size := range imaging.ThumbnailSizes {
+		thumb, err := imaging.Thumbnail(decoded, size)
+		if err == nil {
+			keys = append(keys, thumbnailKey(image.ID, size))
+			err = h.storage.Put(r.Context(), keys[len(keys)-1], bytes.NewReader(thumb))
+		}
+		if err != nil {
+			deleteObjects(r.Context(), h.storage, keys)
+			internalError(w, r, "failed to store image", err)
+			return
+		}
+	}
+
+	created, err := h.images.Create(r.Context(), image)
+	if err != nil {
+		deleteObjects(r.Context(), h.storage, keys)
+		internalError(w, r, "failed to store image", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newImageResponse(*created))
+}
+
+// deleteImages removes the original and thumbnails of every image.
+func deleteImages(ctx context.Context, store storage.Storage, ids []uuid.UUID) {
+	for _, id := range ids {
+		keys := []string{id.String()}
+		for _, size := range imaging.ThumbnailSizes {
+			keys = append(keys, thumbnailKey(id, size))
+		}
+		deleteObjects(ctx, store, keys)
+	}
+}
+
+// deleteObjects removes the stored objects, failures are only logged since
+// the objects are unreachable either way.
+func deleteObjects(ctx context.Context, store storage.Storage, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Warn("failed to clean up stored image", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/nerfthisdev/backend-test-task/internal/httpx"
+	"github.com/nerfthisdev/backend-test-task/internal/logging"
+)
+
+const instrumentation = "github.com/nerfthisdev/backend-test-task/internal/tracing"
+
+// untraced are probe and scrape endpoints hit every few seconds, their spans
+// would only bury the interesting ones.
+var untraced = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Middleware starts a server span per request, continuing the trace from
+// incoming traceparent headers. It must wrap the ServeMux itself so the span
+// can be named after the matched route pattern. The trace id is added to the
+// request logger.
+func Middleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer(instrumentation)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if untraced[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				semconv.ClientAddress(r.RemoteAddr),
+			),
+		)
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.IsValid() {
+			logging.AddFields(ctx, zap.String("trace_id", sc.TraceID().String()))
+		}
+
+		rec := httpx.Record(w)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		if route := httpx.Route(r); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := rec.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
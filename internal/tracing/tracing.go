@@ -0,0 +1,82 @@
+// Package tracing configures OpenTelemetry and instruments HTTP handlers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const serviceName = "marketplace"
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. With ExporterNone the global no-op provider stays in place
+// and spans cost next to nothing. The returned function flushes pending spans
+// and must be called on shutdown.
+func Setup(ctx context.Context, exporter, file string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var out io.Writer
+	var closeOut func() error
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		out, closeOut = os.Stdout, func() error { return nil }
+	case ExporterFile:
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		out, closeOut = f, f.Close
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		closeOut()
+		return nil, fmt.Errorf("failed to init trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		closeOut()
+		return nil, fmt.Errorf("failed to init trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if cerr := closeOut(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,265 @@
+// Package validation checks request DTOs against rules declared in their
+// `validate` struct tags and reports every failure at once.
+//
+// Supported rules:
+//
+//	required  value must be present: non-zero, non-empty or non-nil
+//	min=N     strings: at least N characters, slices: at least N items,
+//	          numbers: at least N
+//	max=N     the upper bound counterpart of min
+//	alnum     only latin letters and digits
+//	letter    contains at least one latin letter
+//	digit     contains at least one digit
+//	unique    slice items do not repeat
+//
+// Nil pointers and nil slices are treated as absent and skip every rule but
+// required, which lets PATCH style DTOs declare the same rules as create ones.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+var (
+	alnumRe  = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	letterRe = regexp.MustCompile(`[A-Za-z]`)
+	digitRe  = regexp.MustCompile(`[0-9]`)
+)
+
+type rule struct {
+	name  string
+	param float64
+}
+
+type field struct {
+	index int
+	name  string
+	rules []rule
+}
+
+// fieldsCache maps a struct type to its parsed rules so tags are only parsed
+// once per DTO type.
+var fieldsCache sync.Map
+
+// MustRegister parses the rules of every DTO type up front and panics on a
+// malformed tag, so a bad tag stops the service at startup instead of
+// failing a live request.
+func MustRegister(dtos ...any) {
+	for _, v := range dtos {
+		t := reflect.TypeOf(v)
+		if t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("validation: %T is not a struct", v))
+		}
+		if _, err := fieldsOf(t); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// Struct validates v, which must be a struct or a pointer to one, and
+// returns the failures in field declaration order. It panics if v is not a
+// struct or its tags are malformed, register the type with MustRegister to
+// catch that at startup.
+func Struct(v any) []problem.FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+
+	fields, err := fieldsOf(rv.Type())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	var errs []problem.FieldError
+	for _, f := range fields {
+		errs = append(errs, f.check(rv.Field(f.index))...)
+	}
+	return errs
+}
+
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.([]field), nil
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || tag == "" {
+			continue
+		}
+
+		name := sf.Name
+		if jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ","); jsonName != "" && jsonName != "-" {
+			name = jsonName
+		}
+
+		f := field{index: i, name: name}
+		for _, r := range strings.Split(tag, ",") {
+			parsed, err := parseRule(r, sf.Type)
+			if err != nil {
+				return nil, fmt.Errorf("validation: %s.%s: %w", t.Name(), sf.Name, err)
+			}
+			f.rules = append(f.rules, parsed)
+		}
+		fields = append(fields, f)
+	}
+
+	fieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+// parseRule parses a single rule and checks that it applies to fields of
+// type t.
+func parseRule(r string, t reflect.Type) (rule, error) {
+	name, param, hasParam := strings.Cut(r, "=")
+	parsed := rule{name: name}
+
+	switch name {
+	case "min", "max":
+		if !hasParam {
+			return rule{}, fmt.Errorf("%q needs a parameter", r)
+		}
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return rule{}, fmt.Errorf("bad parameter in %q", r)
+		}
+		parsed.param = p
+	case "required", "alnum", "letter", "digit", "unique":
+		if hasParam {
+			return rule{}, fmt.Errorf("%q takes no parameter", r)
+		}
+	default:
+		return rule{}, fmt.Errorf("unknown rule %q", r)
+	}
+
+	// rules other than required look through pointers, see check
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var ok bool
+	switch name {
+	case "required":
+		ok = true
+	case "min", "max":
+		switch t.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			ok = true
+		}
+	case "alnum", "letter", "digit":
+		ok = t.Kind() == reflect.String
+	case "unique":
+		ok = (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Comparable()
+	}
+	if !ok {
+		return rule{}, fmt.Errorf("%q does not apply to %s", r, t)
+	}
+
+	return parsed, nil
+}
+
+func (f field) check(v reflect.Value) []problem.FieldError {
+	absent := (v.Kind() == reflect.Pointer || v.Kind() == reflect.Slice) && v.IsNil()
+	for _, r := range f.rules {
+		if r.name == "required" && (absent || v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0)) {
+			return []problem.FieldError{f.fail(problem.FieldRequired, "%s is required", f.name)}
+		}
+	}
+	if absent {
+		return nil
+	}
+	v = reflect.Indirect(v)
+
+	var errs []problem.FieldError
+	for _, r := range f.rules {
+		if err, failed := f.apply(r, v); failed {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (f field) apply(r rule, v reflect.Value) (problem.FieldError, bool) {
+	switch r.name {
+	case "min", "max":
+		return f.bound(r, v)
+	case "alnum":
+		if !alnumRe.MatchString(v.String()) {
+			return f.fail(problem.FieldInvalid, "%s may contain only latin letters and digits", f.name), true
+		}
+	case "letter":
+		if !letterRe.MatchString(v.String()) {
+			return f.fail(problem.FieldInvalid, "%s must contain a latin letter", f.name), true
+		}
+	case "digit":
+		if !digitRe.MatchString(v.String()) {
+			return f.fail(problem.FieldInvalid, "%s must contain a digit", f.name), true
+		}
+	case "unique":
+		seen := make(map[any]bool, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i).Interface()
+			if seen[item] {
+				return f.fail(problem.FieldDuplicate, "%s must not contain duplicates", f.name), true
+			}
+			seen[item] = true
+		}
+	}
+	return problem.FieldError{}, false
+}
+
+func (f field) bound(r rule, v reflect.Value) (problem.FieldError, bool) {
+	isMin := r.name == "min"
+	limit := strconv.FormatFloat(r.param, 'f', -1, 64)
+
+	var n float64
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array:
+		n, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	}
+
+	if isMin && n < r.param {
+		code := problem.FieldTooSmall
+		if unit != "" {
+			code = problem.FieldTooShort
+		}
+		return f.fail(code, "%s must be at least %s%s", f.name, limit, unit), true
+	}
+	if !isMin && n > r.param {
+		code := problem.FieldTooLarge
+		if unit != "" {
+			code = problem.FieldTooLong
+		}
+		return f.fail(code, "%s must be at most %s%s", f.name, limit, unit), true
+	}
+	return problem.FieldError{}, false
+}
+
+func (f field) fail(code, format string, args ...any) problem.FieldError {
+	return problem.FieldError{Field: f.name, Code: code, Message: fmt.Sprintf(format, args...)}
+}
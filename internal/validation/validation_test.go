@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nerfthisdev/backend-test-task/internal/problem"
+)
+
+type account struct {
+	Login    string `json:"login" validate:"required,min=3,max=5,alnum"`
+	Password string `json:"password" validate:"letter,digit"`
+}
+
+type listing struct {
+	Title  string   `json:"title" validate:"max=4"`
+	Price  float64  `json:"price" validate:"min=0,max=10.5"`
+	Count  int      `json:"count" validate:"min=1"`
+	Size   uint     `json:"size" validate:"max=3"`
+	Tags   []string `json:"tags" validate:"required,min=1,max=2,unique"`
+	Note   *string  `json:"note" validate:"min=2"`
+	Hidden string   `validate:"required"`
+	Free   string   `json:"free"`
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// codes returns "field:code" for every failure, in order.
+func codes(errs []problem.FieldError) []string {
+	out := []string{}
+	for _, e := range errs {
+		out = append(out, e.Field+":"+e.Code)
+	}
+	return out
+}
+
+func validListing() listing {
+	return listing{Title: "lamp", Price: 0, Count: 1, Size: 3, Tags: []string{"a", "b"}, Hidden: "x"}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []string
+	}{
+		{"valid account", account{Login: "abc12", Password: "a1"}, []string{}},
+		{"pointer to struct", &account{Login: "abc", Password: "a1"}, []string{}},
+		{"required stops other rules", account{Password: "a1"}, []string{"login:required"}},
+		{"min boundary", account{Login: "ab", Password: "a1"}, []string{"login:too_short"}},
+		{"max boundary", account{Login: "abcdef", Password: "a1"}, []string{"login:too_long"}},
+		{"alnum", account{Login: "ab_c", Password: "a1"}, []string{"login:invalid"}},
+		{"alnum rejects non latin", account{Login: "абв", Password: "a1"}, []string{"login:invalid"}},
+		{"letter and digit", account{Login: "abc", Password: "!!"}, []string{"password:invalid", "password:invalid"}},
+		{"all failures at once", account{Login: "a_", Password: "1"}, []string{"login:too_short", "login:invalid", "password:invalid"}},
+
+		{"valid listing", validListing(), []string{}},
+		{"string length counts runes", func() listing { l := validListing(); l.Title = "ламп"; return l }(), []string{}},
+		{"string too long", func() listing { l := validListing(); l.Title = "lamps"; return l }(), []string{"title:too_long"}},
+		{"float below min", func() listing { l := validListing(); l.Price = -0.01; return l }(), []string{"price:too_small"}},
+		{"float at max", func() listing { l := validListing(); l.Price = 10.5; return l }(), []string{}},
+		{"float above max", func() listing { l := validListing(); l.Price = 10.51; return l }(), []string{"price:too_large"}},
+		{"int below min", func() listing { l := validListing(); l.Count = 0; return l }(), []string{"count:too_small"}},
+		{"uint above max", func() listing { l := validListing(); l.Size = 4; return l }(), []string{"size:too_large"}},
+		{"nil slice is required", func() listing { l := validListing(); l.Tags = nil; return l }(), []string{"tags:required"}},
+		{"empty slice is required", func() listing { l := validListing(); l.Tags = []string{}; return l }(), []string{"tags:required"}},
+		{"too many items", func() listing { l := validListing(); l.Tags = []string{"a", "b", "c"}; return l }(), []string{"tags:too_long"}},
+		{"duplicates", func() listing { l := validListing(); l.Tags = []string{"a", "a"}; return l }(), []string{"tags:duplicate"}},
+		{"nil pointer skips rules", func() listing { l := validListing(); l.Note = nil; return l }(), []string{}},
+		{"pointer is dereferenced", func() listing { l := validListing(); l.Note = ptr("x"); return l }(), []string{"note:too_short"}},
+		{"pointer at min", func() listing { l := validListing(); l.Note = ptr("xy"); return l }(), []string{}},
+		{"go name without json tag", func() listing { l := validListing(); l.Hidden = ""; return l }(), []string{"Hidden:required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(Struct(tt.v))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructMessages(t *testing.T) {
+	errs := Struct(account{Login: "abcdefgh", Password: "a1"})
+	if len(errs) != 1 || errs[0].Message != "login must be at most 5 characters" {
+		t.Errorf("Struct() = %+v", errs)
+	}
+}
+
+func TestBadTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"unknown rule", struct {
+			A string `validate:"email"`
+		}{}, `unknown rule "email"`},
+		{"bad parameter", struct {
+			A string `validate:"min=x"`
+		}{}, `bad parameter in "min=x"`},
+		{"missing parameter", struct {
+			A string `validate:"max"`
+		}{}, `"max" needs a parameter`},
+		{"unexpected parameter", struct {
+			A string `validate:"required=1"`
+		}{}, `"required=1" takes no parameter`},
+		{"empty rule", struct {
+			A string `validate:"required,"`
+		}{}, `unknown rule ""`},
+		{"bound on bool", struct {
+			A bool `validate:"min=1"`
+		}{}, `"min=1" does not apply to bool`},
+		{"string rule on int", struct {
+			A int `validate:"alnum"`
+		}{}, `"alnum" does not apply to int`},
+		{"unique on string", struct {
+			A string `validate:"unique"`
+		}{}, `"unique" does not apply to string`},
+		{"unique on incomparable items", struct {
+			A [][]int `validate:"unique"`
+		}{}, `"unique" does not apply to [][]int`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				msg, _ := r.(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Errorf("MustRegister panic = %v, want it to mention %s", r, tt.want)
+				}
+			}()
+			MustRegister(tt.v)
+		})
+	}
+}
+
+func TestBadTagsPanicInStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Struct() did not panic on an unknown rule")
+		}
+	}()
+	Struct(struct {
+		A string `validate:"nope"`
+	}{})
+}
+
+func TestNotAStruct(t *testing.T) {
+	for _, v := range []any{nil, 1, "s", ptr(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("MustRegister(%T) did not panic", v)
+				}
+			}()
+			MustRegister(v)
+		}()
+	}
+}
+
+func TestMustRegisterAcceptsValidTypes(t *testing.T) {
+	MustRegister(account{}, &listing{})
+}
@@ -0,0 +1,11 @@
+CREATE TABLE refresh_tokens (
+    session_id UUID PRIMARY KEY,
+    user_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX refresh_tokens_user_guid_idx ON refresh_tokens (user_guid);
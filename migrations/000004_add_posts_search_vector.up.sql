@@ -0,0 +1,8 @@
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', title), 'A') ||
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('russian', description), 'B') ||
+    setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
@@ -0,0 +1,36 @@
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL
+);
+
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
+
+INSERT INTO categories (slug, name) VALUES
+    ('electronics', 'Electronics'),
+    ('real-estate', 'Real estate'),
+    ('transport', 'Transport'),
+    ('home', 'Home and garden'),
+    ('other', 'Other');
+
+INSERT INTO categories (parent_id, slug, name)
+SELECT c.id, v.slug, v.name
+FROM (VALUES
+    ('electronics', 'phones', 'Phones'),
+    ('electronics', 'laptops', 'Laptops'),
+    ('electronics', 'audio', 'Audio and video'),
+    ('real-estate', 'apartments', 'Apartments'),
+    ('real-estate', 'houses', 'Houses'),
+    ('transport', 'cars', 'Cars'),
+    ('transport', 'motorcycles', 'Motorcycles'),
+    ('home', 'furniture', 'Furniture'),
+    ('home', 'appliances', 'Appliances')
+) AS v (parent_slug, slug, name)
+JOIN categories c ON c.slug = v.parent_slug;
+
+ALTER TABLE posts ADD COLUMN category_id INT REFERENCES categories(id);
+UPDATE posts SET category_id = (SELECT id FROM categories WHERE slug = 'other');
+ALTER TABLE posts ALTER COLUMN category_id SET NOT NULL;
+
+CREATE INDEX posts_category_id_idx ON posts (category_id);
@@ -0,0 +1,9 @@
+CREATE TABLE images (
+    id UUID PRIMARY KEY,
+    user_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE posts ADD COLUMN image_id UUID REFERENCES images(id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE posts ADD COLUMN image_id UUID REFERENCES images(id) ON DELETE SET NULL;
+
+UPDATE posts p SET image_id = ai.image_id
+FROM ad_images ai WHERE ai.post_id = p.id AND ai.position = 0;
+
+DROP TABLE ad_images;
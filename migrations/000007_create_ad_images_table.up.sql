@@ -0,0 +1,12 @@
+CREATE TABLE ad_images (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    position INT NOT NULL CHECK (position >= 0),
+    PRIMARY KEY (post_id, image_id),
+    UNIQUE (post_id, position)
+);
+
+INSERT INTO ad_images (post_id, image_id, position)
+SELECT id, image_id, 0 FROM posts WHERE image_id IS NOT NULL;
+
+ALTER TABLE posts DROP COLUMN image_id;
@@ -0,0 +1,6 @@
+DROP TABLE login_attempts;
+
+ALTER TABLE users
+    DROP COLUMN locked_until,
+    DROP COLUMN lockouts,
+    DROP COLUMN failed_logins;
@@ -0,0 +1,14 @@
+ALTER TABLE users
+    ADD COLUMN failed_logins INT NOT NULL DEFAULT 0,
+    ADD COLUMN lockouts INT NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until TIMESTAMPTZ;
+
+CREATE TABLE login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    user_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX login_attempts_user_guid_created_at_idx ON login_attempts (user_guid, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN ban_reason,
+    DROP COLUMN banned_at,
+    DROP COLUMN role;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    ADD COLUMN banned_at TIMESTAMPTZ,
+    ADD COLUMN ban_reason TEXT NOT NULL DEFAULT '';
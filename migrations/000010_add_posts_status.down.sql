@@ -0,0 +1,8 @@
+DROP INDEX posts_user_guid_idx;
+DROP INDEX posts_status_changed_at_idx;
+DROP INDEX posts_status_created_at_idx;
+
+ALTER TABLE posts
+    DROP COLUMN status_changed_at,
+    DROP COLUMN rejection_reason,
+    DROP COLUMN status;
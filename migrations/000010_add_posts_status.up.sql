@@ -0,0 +1,13 @@
+ALTER TABLE posts
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending_review'
+        CHECK (status IN ('draft', 'pending_review', 'published', 'rejected', 'archived', 'sold')),
+    ADD COLUMN rejection_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN status_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- everything already listed stays visible
+UPDATE posts SET status = 'published';
+
+CREATE INDEX posts_status_created_at_idx ON posts (status, created_at);
+-- the moderation queue is ordered by when ads entered review
+CREATE INDEX posts_status_changed_at_idx ON posts (status, status_changed_at);
+CREATE INDEX posts_user_guid_idx ON posts (user_guid);
@@ -0,0 +1,3 @@
+DROP INDEX posts_published_expires_at_idx;
+
+ALTER TABLE posts DROP COLUMN expires_at;
@@ -0,0 +1,6 @@
+-- the default only backfills existing ads with a full lifetime from the
+-- moment of the migration, new ads get expires_at from AD_LIFETIME
+ALTER TABLE posts ADD COLUMN expires_at TIMESTAMPTZ NOT NULL DEFAULT NOW() + INTERVAL '30 days';
+ALTER TABLE posts ALTER COLUMN expires_at DROP DEFAULT;
+
+CREATE INDEX posts_published_expires_at_idx ON posts (expires_at) WHERE status = 'published';
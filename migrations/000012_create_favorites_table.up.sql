@@ -0,0 +1,8 @@
+CREATE TABLE favorites (
+    user_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_guid, post_id)
+);
+
+CREATE INDEX favorites_post_id_idx ON favorites (post_id);
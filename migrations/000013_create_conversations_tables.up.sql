@@ -0,0 +1,26 @@
+CREATE TABLE conversations (
+    id BIGSERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    buyer_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    seller_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    -- the last message each side has read, unread ones come after it
+    buyer_last_read BIGINT NOT NULL DEFAULT 0,
+    seller_last_read BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_message_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (post_id, buyer_guid),
+    CHECK (buyer_guid <> seller_guid)
+);
+
+CREATE INDEX conversations_buyer_guid_idx ON conversations (buyer_guid, last_message_at DESC);
+CREATE INDEX conversations_seller_guid_idx ON conversations (seller_guid, last_message_at DESC);
+
+CREATE TABLE messages (
+    id BIGSERIAL PRIMARY KEY,
+    conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_guid UUID NOT NULL REFERENCES users(guid) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX messages_conversation_id_idx ON messages (conversation_id, id);